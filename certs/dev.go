@@ -0,0 +1,113 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"time"
+)
+
+const (
+	devCALifetime   = time.Hour * 24 * 365
+	devLeafLifetime = time.Hour * 24 * 90
+)
+
+// DevCertificate is a PEM encoded certificate and its private key.
+type DevCertificate struct {
+	Cert []byte
+	Key  []byte
+}
+
+// GenerateDev generates a self-signed CA and a leaf certificate it signs for the hosts,
+// for local development only.
+func GenerateDev(hosts []string) (ca *DevCertificate, leaf *DevCertificate, err error) {
+	now := time.Now()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	caTemplate := &x509.Certificate{
+		Subject: pkix.Name{
+			Organization: []string{"simpleident development CA"},
+			CommonName:   "simpleident development CA",
+		},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(devCALifetime),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	caCert, err := createCertificate(caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	ca, err = encode(caCert.Raw, caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	leafTemplate := &x509.Certificate{
+		Subject: pkix.Name{
+			Organization: []string{"simpleident development"},
+		},
+		NotBefore:   now.Add(-time.Hour),
+		NotAfter:    now.Add(devLeafLifetime),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			leafTemplate.IPAddresses = append(leafTemplate.IPAddresses, ip)
+		} else {
+			leafTemplate.DNSNames = append(leafTemplate.DNSNames, host)
+		}
+	}
+	if len(hosts) > 0 {
+		leafTemplate.Subject.CommonName = hosts[0]
+	}
+	leafCert, err := createCertificate(leafTemplate, caCert, &leafKey.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	leaf, err = encode(leafCert.Raw, leafKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ca, leaf, nil
+}
+
+func createCertificate(template, parent *x509.Certificate, pub any, priv *ecdsa.PrivateKey) (*x509.Certificate, error) {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	template.SerialNumber = serialNumber
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, priv)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(der)
+}
+
+func encode(der []byte, key *ecdsa.PrivateKey) (*DevCertificate, error) {
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return &DevCertificate{
+		Cert: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		Key:  pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}),
+	}, nil
+}
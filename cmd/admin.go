@@ -0,0 +1,143 @@
+/*
+Copyright © 2024 Takahiro INAGAKI <inagaki0106@gmail.com>
+*/
+package cmd
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/ophum/simpleident/models"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// adminCmd represents the admin command
+var adminCmd = &cobra.Command{
+	Use:   "admin",
+	Short: "Manage the administrators",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return viper.Unmarshal(&config)
+	},
+}
+
+var adminCreateCmd = &cobra.Command{
+	Use:   "create <username>",
+	Short: "Create an administrator or grant the admin role to an existing account",
+	Long: `Create an administrator or grant the admin role to an existing account.
+The admin role has every permission and is created if it does not exist.
+A password is generated and printed if --password is not given when creating a new account.
+Use this to bootstrap the first administrator, who can then manage accounts from the admin UI.`,
+	Args: cobra.ExactArgs(1),
+	RunE: adminCreateCommand,
+}
+
+var adminPassword string
+
+func init() {
+	rootCmd.AddCommand(adminCmd)
+	adminCmd.AddCommand(adminCreateCmd)
+
+	adminCreateCmd.Flags().StringVar(&adminPassword, "password", "", "password of the new account")
+}
+
+func adminCreateCommand(cmd *cobra.Command, args []string) error {
+	db, err := openDatabase(config.Database)
+	if err != nil {
+		return err
+	}
+
+	username := args[0]
+
+	role, err := adminRole(db)
+	if err != nil {
+		return err
+	}
+
+	var account models.Account
+	err = db.Where("username = ?", username).First(&account).Error
+	if err == nil {
+		if err := db.Model(&account).Association("Roles").Append(role); err != nil {
+			return err
+		}
+		fmt.Printf("granted the %s role to %s\n", role.Name, username)
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	password := adminPassword
+	if password == "" {
+		b := make([]byte, 18)
+		if _, err := rand.Read(b); err != nil {
+			return err
+		}
+		password = base64.RawURLEncoding.EncodeToString(b)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+	if err := db.Create(&models.Account{
+		Model: models.Model{
+			ID: id,
+		},
+		Username: username,
+		Password: string(hash),
+		Roles:    []*models.Role{role},
+	}).Error; err != nil {
+		return err
+	}
+
+	fmt.Printf("created administrator %s\n", username)
+	if adminPassword == "" {
+		fmt.Printf("password: %s\n", password)
+	}
+	return nil
+}
+
+// adminRole returns the built-in admin role, creating it with every permission if it was deleted.
+func adminRole(db *gorm.DB) (*models.Role, error) {
+	var role models.Role
+	err := db.Where("name = ?", models.RoleNameAdmin).First(&role).Error
+	if err == nil {
+		return &role, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	permissions := []string{}
+	for _, p := range models.Permissions {
+		permissions = append(permissions, string(p))
+	}
+	role = models.Role{
+		Model: models.Model{
+			ID: id,
+		},
+		Name:        models.RoleNameAdmin,
+		Description: "all permissions",
+		Permissions: strings.Join(permissions, " "),
+	}
+	if err := db.Create(&role).Error; err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
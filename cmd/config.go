@@ -1,10 +1,326 @@
 package cmd
 
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ophum/simpleident/server"
+	"github.com/spf13/viper"
+)
+
 type Config struct {
-	Database *ConfigDatabase
+	// gin mode: debug, release or test. release refuses to start with insecure defaults.
+	Mode string
+	// issuer identifier and public base URL, e.g. https://id.example.com
+	Issuer string
+	// how long /readyz reports the server is shutting down before the listeners are closed,
+	// so that the orchestrator stops routing new requests to it, 0 to close them at once
+	DrainDelay time.Duration `mapstructure:"drain_delay"`
+	// how long in-flight requests are waited for on shutdown
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+	Server          *ConfigListener
+	Admin           *ConfigListener
+	Secrets         *ConfigSecrets
+	Lifetimes       *ConfigLifetimes
+	Database        *ConfigDatabase
+	Keys            *ConfigKeys
+}
+
+type ConfigListener struct {
+	// host:port to listen on
+	Address string
+	// served over plain HTTP if nil
+	TLS *ConfigTLS
+}
+
+type ConfigTLS struct {
+	// reloaded on SIGHUP or when the files change
+	CertFile string `mapstructure:"cert_file"`
+	KeyFile  string `mapstructure:"key_file"`
+	// 1.2 or 1.3, 1.2 if empty
+	MinVersion string `mapstructure:"min_version"`
+	// names of the TLS 1.2 cipher suites, e.g. TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256.
+	// Go's secure defaults if empty. TLS 1.3 cipher suites are not configurable, so they and min_version 1.3 are refused.
+	CipherSuites []string `mapstructure:"cipher_suites"`
+}
+
+type ConfigSecrets struct {
+	// authentication key of the session cookies
+	Cookie string
+	CSRF   string
+}
+
+// lifetimes left unset use the defaults of server.DefaultConfig
+type ConfigLifetimes struct {
+	AuthorizationCode time.Duration `mapstructure:"authorization_code"`
+	AccessToken       time.Duration `mapstructure:"access_token"`
+	RefreshToken      time.Duration `mapstructure:"refresh_token"`
+	IDToken           time.Duration `mapstructure:"id_token"`
+	DeviceCode        time.Duration `mapstructure:"device_code"`
 }
 
 type ConfigDatabase struct {
+	// sqlite3, postgres or mysql
 	Driver string
 	DSN    string
+	// apply pending migrations when the server starts
+	AutoMigrate bool `mapstructure:"auto_migrate"`
+	// connection pool, database/sql defaults if zero
+	MaxOpenConns    int           `mapstructure:"max_open_conns"`
+	MaxIdleConns    int           `mapstructure:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `mapstructure:"conn_max_idle_time"`
+}
+
+type ConfigKeys struct {
+	// signing keys are rotated automatically if set
+	RotationInterval time.Duration `mapstructure:"rotation_interval"`
+	// how long a new key is published in JWKS before it is used
+	PrepublishPeriod time.Duration `mapstructure:"prepublish_period"`
+}
+
+// configEnvKeys can be set by environment variables as well,
+// e.g. SIMPLEIDENT_SECRETS_COOKIE for secrets.cookie.
+var configEnvKeys = []string{
+	"mode",
+	"issuer",
+	"drain_delay",
+	"shutdown_timeout",
+	"server.address",
+	"server.tls.cert_file",
+	"server.tls.key_file",
+	"server.tls.min_version",
+	"server.tls.cipher_suites",
+	"admin.address",
+	"admin.tls.cert_file",
+	"admin.tls.key_file",
+	"admin.tls.min_version",
+	"admin.tls.cipher_suites",
+	"secrets.cookie",
+	"secrets.csrf",
+	"lifetimes.authorization_code",
+	"lifetimes.access_token",
+	"lifetimes.refresh_token",
+	"lifetimes.id_token",
+	"lifetimes.device_code",
+	"database.driver",
+	"database.dsn",
+	"database.auto_migrate",
+	"database.max_open_conns",
+	"database.max_idle_conns",
+	"database.conn_max_lifetime",
+	"database.conn_max_idle_time",
+	"keys.rotation_interval",
+	"keys.prepublish_period",
+}
+
+const (
+	defaultServerAddress   = ":8080"
+	defaultDrainDelay      = time.Second * 5
+	defaultShutdownTimeout = time.Second * 30
+	// used in debug mode only
+	defaultSecret = "secret"
+	// 256 bits
+	minSecretLength = 32
+)
+
+// setDefaults fills in the defaults of the server settings.
+func (c *Config) setDefaults() {
+	if c.Mode == "" {
+		c.Mode = gin.DebugMode
+	}
+	// an explicit 0 disables the delay, so only an unset one gets the default
+	if !viper.IsSet("drain_delay") {
+		c.DrainDelay = defaultDrainDelay
+	}
+	if c.ShutdownTimeout == 0 {
+		c.ShutdownTimeout = defaultShutdownTimeout
+	}
+	if c.Server == nil {
+		c.Server = &ConfigListener{}
+	}
+	if c.Server.Address == "" {
+		c.Server.Address = defaultServerAddress
+	}
+	if c.Secrets == nil {
+		c.Secrets = &ConfigSecrets{}
+	}
+	if c.Mode != gin.ReleaseMode {
+		if c.Secrets.Cookie == "" {
+			c.Secrets.Cookie = defaultSecret
+		}
+		if c.Secrets.CSRF == "" {
+			c.Secrets.CSRF = defaultSecret
+		}
+	}
+	if c.Lifetimes == nil {
+		c.Lifetimes = &ConfigLifetimes{}
+	}
+}
+
+// validate reports every problem of the server settings at once.
+func (c *Config) validate() error {
+	var errs []error
+
+	switch c.Mode {
+	case gin.DebugMode, gin.ReleaseMode, gin.TestMode:
+	default:
+		errs = append(errs, fmt.Errorf("mode: must be one of %s, %s or %s", gin.DebugMode, gin.ReleaseMode, gin.TestMode))
+	}
+	release := c.Mode == gin.ReleaseMode
+
+	if c.Issuer == "" {
+		if release {
+			errs = append(errs, errors.New("issuer: required in release mode"))
+		}
+	} else if err := validateIssuer(c.Issuer, release); err != nil {
+		errs = append(errs, fmt.Errorf("issuer: %w", err))
+	}
+
+	if c.DrainDelay < 0 {
+		errs = append(errs, errors.New("drain_delay: must not be negative"))
+	}
+	if c.ShutdownTimeout < 0 {
+		errs = append(errs, errors.New("shutdown_timeout: must not be negative"))
+	}
+
+	errs = append(errs, c.Server.validate("server")...)
+	if c.Admin != nil && c.Admin.Address != "" {
+		errs = append(errs, c.Admin.validate("admin")...)
+		if c.Admin.Address == c.Server.Address {
+			errs = append(errs, errors.New("admin.address: must differ from server.address"))
+		}
+	}
+
+	if release {
+		for _, secret := range []struct {
+			name  string
+			value string
+		}{
+			{"secrets.cookie", c.Secrets.Cookie},
+			{"secrets.csrf", c.Secrets.CSRF},
+		} {
+			if secret.value == "" || secret.value == defaultSecret {
+				errs = append(errs, fmt.Errorf("%s: required in release mode", secret.name))
+			} else if len(secret.value) < minSecretLength {
+				errs = append(errs, fmt.Errorf("%s: must be at least %d characters", secret.name, minSecretLength))
+			}
+		}
+	}
+
+	for _, lifetime := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"lifetimes.authorization_code", c.Lifetimes.AuthorizationCode},
+		{"lifetimes.access_token", c.Lifetimes.AccessToken},
+		{"lifetimes.refresh_token", c.Lifetimes.RefreshToken},
+		{"lifetimes.id_token", c.Lifetimes.IDToken},
+		{"lifetimes.device_code", c.Lifetimes.DeviceCode},
+	} {
+		if lifetime.value < 0 {
+			errs = append(errs, fmt.Errorf("%s: must not be negative", lifetime.name))
+		}
+	}
+
+	if c.Database == nil || c.Database.Driver == "" || c.Database.DSN == "" {
+		errs = append(errs, errors.New("database: driver and dsn are required"))
+	} else {
+		errs = append(errs, c.Database.validate()...)
+	}
+
+	if c.Keys != nil {
+		if c.Keys.RotationInterval < 0 || c.Keys.PrepublishPeriod < 0 {
+			errs = append(errs, errors.New("keys: durations must not be negative"))
+		}
+		if c.Keys.RotationInterval > 0 && c.Keys.PrepublishPeriod >= c.Keys.RotationInterval {
+			errs = append(errs, errors.New("keys.prepublish_period: must be shorter than keys.rotation_interval"))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *ConfigListener) validate(name string) []error {
+	var errs []error
+	if c.Address == "" {
+		errs = append(errs, fmt.Errorf("%s.address: required", name))
+	}
+	if c.TLS != nil {
+		if c.TLS.CertFile == "" || c.TLS.KeyFile == "" {
+			errs = append(errs, fmt.Errorf("%s.tls: cert_file and key_file are required", name))
+		}
+		if _, err := c.TLS.tlsConfig(); err != nil {
+			errs = append(errs, fmt.Errorf("%s.tls: %w", name, err))
+		}
+	}
+	return errs
+}
+
+func (c *ConfigDatabase) validate() []error {
+	var errs []error
+	if !slices.Contains(databaseDrivers, c.Driver) {
+		errs = append(errs, fmt.Errorf("database.driver: must be one of %s", strings.Join(databaseDrivers, ", ")))
+	}
+	if c.MaxOpenConns < 0 || c.MaxIdleConns < 0 {
+		errs = append(errs, errors.New("database: connection counts must not be negative"))
+	}
+	if c.MaxOpenConns > 0 && c.MaxIdleConns > c.MaxOpenConns {
+		errs = append(errs, errors.New("database.max_idle_conns: must not exceed database.max_open_conns"))
+	}
+	if c.ConnMaxLifetime < 0 || c.ConnMaxIdleTime < 0 {
+		errs = append(errs, errors.New("database: durations must not be negative"))
+	}
+	return errs
+}
+
+// validateIssuer checks the issuer identifier (OpenID Connect Discovery 1.0 3).
+func validateIssuer(issuer string, release bool) error {
+	u, err := url.Parse(issuer)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "https" && (release || u.Scheme != "http") {
+		return errors.New("must be an https URL")
+	}
+	if u.Host == "" {
+		return errors.New("host required")
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return errors.New("must not have a query or fragment")
+	}
+	return nil
+}
+
+// serverConfig returns the settings of the server package, falling back to its defaults.
+func (c *Config) serverConfig() *server.Config {
+	serverConfig := server.DefaultConfig()
+	serverConfig.EnableAdmin = c.Admin == nil || c.Admin.Address == ""
+	serverConfig.Issuer = strings.TrimSuffix(c.Issuer, "/")
+	if c.Secrets != nil && c.Secrets.CSRF != "" {
+		serverConfig.CSRFSecret = c.Secrets.CSRF
+	}
+
+	if l := c.Lifetimes; l != nil {
+		for _, v := range []struct {
+			dst *time.Duration
+			src time.Duration
+		}{
+			{&serverConfig.AuthorizationCodeLifetime, l.AuthorizationCode},
+			{&serverConfig.AccessTokenLifetime, l.AccessToken},
+			{&serverConfig.RefreshTokenLifetime, l.RefreshToken},
+			{&serverConfig.IDTokenLifetime, l.IDToken},
+			{&serverConfig.DeviceCodeLifetime, l.DeviceCode},
+		} {
+			if v.src > 0 {
+				*v.dst = v.src
+			}
+		}
+	}
+	return serverConfig
 }
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+var databaseDrivers = []string{"sqlite3", "postgres", "mysql"}
+
+func openDatabase(config *ConfigDatabase) (*gorm.DB, error) {
+	if config == nil {
+		return nil, fmt.Errorf("database is not configured")
+	}
+
+	var dialector gorm.Dialector
+	switch config.Driver {
+	case "sqlite3":
+		dialector = sqlite.Open(config.DSN)
+	case "postgres":
+		dialector = postgres.Open(config.DSN)
+	case "mysql":
+		dsnConfig, err := mysqldriver.ParseDSN(config.DSN)
+		if err != nil {
+			return nil, err
+		}
+		// DATETIME columns are scanned into time.Time
+		dsnConfig.ParseTime = true
+		dialector = mysql.Open(dsnConfig.FormatDSN())
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", config.Driver)
+	}
+
+	db, err := gorm.Open(dialector)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(config.MaxOpenConns)
+	// zero would disable idle connections instead of keeping the default
+	if config.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(config.MaxIdleConns)
+	}
+	sqlDB.SetConnMaxLifetime(config.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+	return db, nil
+}
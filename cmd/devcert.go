@@ -0,0 +1,64 @@
+/*
+Copyright © 2024 Takahiro INAGAKI <inagaki0106@gmail.com>
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ophum/simpleident/certs"
+	"github.com/spf13/cobra"
+)
+
+var devCertCmd = &cobra.Command{
+	Use:   "dev-cert",
+	Short: "Generate a self-signed CA and a server certificate for local development",
+	Long: `Generate a self-signed CA and a server certificate for local development.
+Writes ca.pem, ca-key.pem, cert.pem and key.pem to the output directory.
+Trust ca.pem in the browser and set cert.pem and key.pem to server.tls.
+Never use them in production.`,
+	RunE: devCertCommand,
+}
+
+var (
+	devCertOutput string
+	devCertHosts  []string
+)
+
+func init() {
+	rootCmd.AddCommand(devCertCmd)
+
+	devCertCmd.Flags().StringVar(&devCertOutput, "out", ".", "output directory")
+	devCertCmd.Flags().StringSliceVar(&devCertHosts, "hosts", []string{"localhost", "127.0.0.1", "::1"}, "host names and IP addresses of the server certificate")
+}
+
+func devCertCommand(cmd *cobra.Command, args []string) error {
+	ca, leaf, err := certs.GenerateDev(devCertHosts)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(devCertOutput, 0o755); err != nil {
+		return err
+	}
+
+	for _, file := range []struct {
+		name string
+		data []byte
+		perm os.FileMode
+	}{
+		{"ca.pem", ca.Cert, 0o644},
+		{"ca-key.pem", ca.Key, 0o600},
+		{"cert.pem", leaf.Cert, 0o644},
+		{"key.pem", leaf.Key, 0o600},
+	} {
+		name := filepath.Join(devCertOutput, file.name)
+		if err := os.WriteFile(name, file.data, file.perm); err != nil {
+			return err
+		}
+		fmt.Println("wrote", name)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+/*
+Copyright © 2024 Takahiro INAGAKI <inagaki0106@gmail.com>
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/ophum/simpleident/keys"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+// keysCmd represents the keys command
+var keysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "Manage the signing keys",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return viper.Unmarshal(&config)
+	},
+}
+
+var keysRotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Activate a new signing key and retire the current one",
+	Long: `Activate a new signing key and retire the current one.
+
+A pending key is activated if there is one, otherwise a new key is generated.
+The retired key stays published in JWKS until the tokens it signed have expired.
+Running servers pick up the new key within a minute.`,
+	RunE: keysRotateCommand,
+}
+
+var keysListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the signing keys",
+	RunE:  keysListCommand,
+}
+
+var keysImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import a PEM encoded private key as a pending signing key",
+	Args:  cobra.ExactArgs(1),
+	RunE:  keysImportCommand,
+}
+
+var (
+	keysAlgorithms []string
+	keysAlgorithm  string
+	keysActivate   bool
+)
+
+func init() {
+	rootCmd.AddCommand(keysCmd)
+	keysCmd.AddCommand(keysRotateCmd)
+	keysCmd.AddCommand(keysListCmd)
+	keysCmd.AddCommand(keysImportCmd)
+
+	keysRotateCmd.Flags().StringSliceVar(&keysAlgorithms, "alg", []string{string(keys.RS256), string(keys.ES256)}, "algorithms to rotate")
+	keysImportCmd.Flags().StringVar(&keysAlgorithm, "alg", string(keys.RS256), "algorithm of the key")
+	keysImportCmd.Flags().BoolVar(&keysActivate, "activate", false, "activate the imported key immediately")
+}
+
+func newKeyManager(db *gorm.DB) *keys.Manager {
+	keysConfig := &keys.Config{
+		TokenLifetime: config.serverConfig().SignedTokenLifetime(),
+	}
+	if config.Keys != nil {
+		keysConfig.RotationInterval = config.Keys.RotationInterval
+		keysConfig.PrepublishPeriod = config.Keys.PrepublishPeriod
+	}
+	return keys.NewManager(db, keysConfig)
+}
+
+func keysRotateCommand(cmd *cobra.Command, args []string) error {
+	db, err := openDatabase(config.Database)
+	if err != nil {
+		return err
+	}
+	keyManager := newKeyManager(db)
+
+	for _, alg := range keysAlgorithms {
+		key, err := keyManager.Rotate(jose.SignatureAlgorithm(alg))
+		if err != nil {
+			return fmt.Errorf("%s: %w", alg, err)
+		}
+		fmt.Printf("%s: activated %s\n", alg, key.KeyID)
+	}
+	return nil
+}
+
+func keysListCommand(cmd *cobra.Command, args []string) error {
+	db, err := openDatabase(config.Database)
+	if err != nil {
+		return err
+	}
+	keyManager := newKeyManager(db)
+
+	signingKeys, err := keyManager.List()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "KID\tALG\tSTATE\tCREATED\tACTIVATED\tRETIRE AT")
+	for _, key := range signingKeys {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			key.KeyID,
+			key.Algorithm,
+			key.State,
+			key.CreatedAt.Format(time.RFC3339),
+			formatOptionalTime(key.ActivatedAt),
+			formatOptionalTime(key.RetireAt),
+		)
+	}
+	return w.Flush()
+}
+
+func keysImportCommand(cmd *cobra.Command, args []string) error {
+	db, err := openDatabase(config.Database)
+	if err != nil {
+		return err
+	}
+	keyManager := newKeyManager(db)
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	alg := jose.SignatureAlgorithm(keysAlgorithm)
+	key, err := keyManager.Import(alg, data)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s: imported %s\n", alg, key.KeyID)
+
+	if keysActivate {
+		key, err := keyManager.Rotate(alg)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s: activated %s\n", alg, key.KeyID)
+	}
+	return nil
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
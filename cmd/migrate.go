@@ -0,0 +1,153 @@
+/*
+Copyright © 2024 Takahiro INAGAKI <inagaki0106@gmail.com>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ophum/simpleident/migrations"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+// migrateCmd represents the migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Manage the database schema",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return viper.Unmarshal(&config)
+	},
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply pending migrations",
+	RunE:  migrateUpCommand,
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Roll back applied migrations",
+	RunE:  migrateDownCommand,
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the migrations and whether they have been applied",
+	RunE:  migrateStatusCommand,
+}
+
+var (
+	migrateUpLimit   int
+	migrateDownLimit int
+)
+
+// how long to wait for another process migrating the same database
+const migrateLockTimeout = time.Minute * 5
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+	migrateCmd.AddCommand(migrateUpCmd)
+	migrateCmd.AddCommand(migrateDownCmd)
+	migrateCmd.AddCommand(migrateStatusCmd)
+
+	migrateUpCmd.Flags().IntVar(&migrateUpLimit, "limit", 0, "maximum number of migrations to apply, 0 for all")
+	migrateDownCmd.Flags().IntVar(&migrateDownLimit, "limit", 1, "maximum number of migrations to roll back, 0 for all")
+}
+
+func migrateUpCommand(cmd *cobra.Command, args []string) error {
+	db, err := openDatabase(config.Database)
+	if err != nil {
+		return err
+	}
+
+	n, err := migrateWithLock(cmd.Context(), db, func() (int, error) {
+		return migrations.Up(db, migrateUpLimit)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("applied %d migrations\n", n)
+	return nil
+}
+
+func migrateDownCommand(cmd *cobra.Command, args []string) error {
+	db, err := openDatabase(config.Database)
+	if err != nil {
+		return err
+	}
+
+	n, err := migrateWithLock(cmd.Context(), db, func() (int, error) {
+		return migrations.Down(db, migrateDownLimit)
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("rolled back %d migrations\n", n)
+	return nil
+}
+
+func migrateStatusCommand(cmd *cobra.Command, args []string) error {
+	db, err := openDatabase(config.Database)
+	if err != nil {
+		return err
+	}
+
+	statuses, err := migrations.List(db)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "MIGRATION\tAPPLIED AT")
+	for _, s := range statuses {
+		appliedAt := "pending"
+		if s.AppliedAt != nil {
+			appliedAt = s.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\n", s.ID, appliedAt)
+	}
+	return w.Flush()
+}
+
+// autoMigrate applies pending migrations on server startup.
+func autoMigrate(ctx context.Context, db *gorm.DB) error {
+	n, err := migrateWithLock(ctx, db, func() (int, error) {
+		return migrations.Up(db, 0)
+	})
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Printf("applied %d migrations\n", n)
+	}
+	return nil
+}
+
+// migrateWithLock runs fn while holding the migration lock, so that concurrent instances don't race.
+func migrateWithLock(ctx context.Context, db *gorm.DB, fn func() (int, error)) (int, error) {
+	hostname, _ := os.Hostname()
+	owner := fmt.Sprintf("%s:%d", hostname, os.Getpid())
+
+	lockCtx, cancel := context.WithTimeout(ctx, migrateLockTimeout)
+	defer cancel()
+
+	unlock, err := migrations.Lock(lockCtx, db, owner)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err := unlock(); err != nil {
+			log.Println("failed to release the migration lock:", err)
+		}
+	}()
+
+	return fn()
+}
@@ -24,6 +24,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -86,7 +87,13 @@ func initConfig() {
 		viper.SetConfigName(".simpleident")
 	}
 
+	viper.SetEnvPrefix("simpleident")
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	viper.AutomaticEnv() // read in environment variables that match
+	// Unmarshal only sees the keys viper knows about
+	for _, key := range configEnvKeys {
+		cobra.CheckErr(viper.BindEnv(key))
+	}
 
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
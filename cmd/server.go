@@ -4,19 +4,26 @@ Copyright © 2024 Takahiro INAGAKI <inagaki0106@gmail.com>
 package cmd
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"html/template"
+	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-contrib/sessions/cookie"
 	"github.com/gin-gonic/gin"
 	"github.com/ophum/simpleident/assets"
+	"github.com/ophum/simpleident/certs"
 	"github.com/ophum/simpleident/server"
 	"github.com/ophum/simpleident/templates"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"gorm.io/driver/sqlite"
-	"gorm.io/gorm"
 )
 
 // serverCmd represents the server command
@@ -30,7 +37,14 @@ Cobra is a CLI library for Go that empowers applications.
 This application is a tool to generate the needed files
 to quickly create a Cobra application.`,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
-		return viper.Unmarshal(&config)
+		if err := viper.Unmarshal(&config); err != nil {
+			return err
+		}
+		config.setDefaults()
+		if err := config.validate(); err != nil {
+			return fmt.Errorf("invalid config:\n%w", err)
+		}
+		return nil
 	},
 	RunE: serverCommand,
 }
@@ -50,16 +64,99 @@ func init() {
 }
 
 func serverCommand(cmd *cobra.Command, args []string) error {
-	var db *gorm.DB
-	var err error
-	switch config.Database.Driver {
-	case "sqlite3":
-		db, err = gorm.Open(sqlite.Open(config.Database.DSN))
+	gin.SetMode(config.Mode)
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	db, err := openDatabase(config.Database)
+	if err != nil {
+		return err
+	}
+
+	if config.Database.AutoMigrate {
+		if err := autoMigrate(ctx, db); err != nil {
+			return err
+		}
+	}
+
+	keyManager := newKeyManager(db)
+	if err := keyManager.Init(); err != nil {
+		return err
+	}
+	go keyManager.Run(ctx, time.Minute)
+
+	serverConfig := config.serverConfig()
+	server := server.NewServer(db, keyManager, serverConfig)
+
+	r := newEngine("simpleident")
+	server.RegisterRoutes(r)
+
+	publicServer, publicCert, err := newHTTPServer(r, config.Server)
+	if err != nil {
+		return err
+	}
+	httpServers := []*http.Server{publicServer}
+	reloaders := []*certs.Reloader{}
+	if publicCert != nil {
+		reloaders = append(reloaders, publicCert)
+	}
+
+	// the admin UI is served on the public listener unless it has an address of its own
+	if !serverConfig.EnableAdmin {
+		adminEngine := newEngine("simpleident_admin")
+		server.RegisterAdminRoutes(adminEngine)
+
+		adminServer, adminCert, err := newHTTPServer(adminEngine, config.Admin)
 		if err != nil {
 			return err
 		}
+		httpServers = append(httpServers, adminServer)
+		if adminCert != nil {
+			reloaders = append(reloaders, adminCert)
+		}
 	}
 
+	for _, r := range reloaders {
+		go r.Watch(ctx, certWatchPeriod)
+	}
+	go reloadCertificatesOnSignal(ctx, reloaders)
+
+	errCh := make(chan error, len(httpServers))
+	for _, srv := range httpServers {
+		go func() {
+			if err := serveHTTP(srv); !errors.Is(err, http.ErrServerClosed) {
+				errCh <- err
+			}
+		}()
+	}
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	// a second signal terminates the process without waiting
+	stop()
+
+	// readiness is reported as false until the orchestrator stops routing requests here,
+	// then in-flight requests such as token exchanges are completed before exiting
+	log.Println("shutting down")
+	server.Drain()
+	time.Sleep(config.DrainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+
+	var errs []error
+	for _, srv := range httpServers {
+		errs = append(errs, srv.Shutdown(shutdownCtx))
+	}
+	return errors.Join(errs...)
+}
+
+func newEngine(sessionName string) *gin.Engine {
 	r := gin.Default()
 	templ := template.Must(template.New("").
 		Delims("{{", "}}").
@@ -69,10 +166,39 @@ func serverCommand(cmd *cobra.Command, args []string) error {
 	r.SetHTMLTemplate(templ)
 	r.StaticFileFS("favicon.ico", "favicon.ico", http.FS(assets.FS))
 
-	store := cookie.NewStore([]byte("secret"))
-	r.Use(sessions.Sessions("simpleident", store))
-	server := server.NewServer(db, true)
-	server.RegisterRoutes(r)
+	store := cookie.NewStore([]byte(config.Secrets.Cookie))
+	r.Use(sessions.Sessions(sessionName, store))
+	return r
+}
+
+// newHTTPServer returns the server and, if it is served over TLS, the reloader of its certificate.
+func newHTTPServer(r *gin.Engine, config *ConfigListener) (*http.Server, *certs.Reloader, error) {
+	srv := &http.Server{
+		Addr:    config.Address,
+		Handler: r,
+	}
+	if config.TLS == nil {
+		return srv, nil, nil
+	}
 
-	return r.Run(":8080")
+	tlsConfig, err := config.TLS.tlsConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	reloader, err := certs.NewReloader(config.TLS.CertFile, config.TLS.KeyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	tlsConfig.GetCertificate = reloader.GetCertificate
+	srv.TLSConfig = tlsConfig
+	return srv, reloader, nil
+}
+
+func serveHTTP(srv *http.Server) error {
+	if srv.TLSConfig != nil {
+		log.Printf("Listening and serving HTTPS on %s\n", srv.Addr)
+		return srv.ListenAndServeTLS("", "")
+	}
+	log.Printf("Listening and serving HTTP on %s\n", srv.Addr)
+	return srv.ListenAndServe()
 }
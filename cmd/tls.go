@@ -0,0 +1,89 @@
+/*
+Copyright © 2024 Takahiro INAGAKI <inagaki0106@gmail.com>
+*/
+package cmd
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/ophum/simpleident/certs"
+)
+
+// how often the certificate files are checked for changes
+const certWatchPeriod = time.Minute
+
+var tlsVersions = map[string]uint16{
+	"":    tls.VersionTLS12,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// tlsConfig returns the TLS settings without the certificate.
+func (c *ConfigTLS) tlsConfig() (*tls.Config, error) {
+	minVersion, ok := tlsVersions[c.MinVersion]
+	if !ok {
+		return nil, fmt.Errorf("unsupported min_version %q", c.MinVersion)
+	}
+
+	// TLS 1.3 cipher suites are not configurable, so neither are cipher suites for a TLS 1.3 only listener.
+	if len(c.CipherSuites) > 0 && minVersion == tls.VersionTLS13 {
+		return nil, fmt.Errorf("cipher_suites cannot be set with min_version %q", c.MinVersion)
+	}
+
+	var cipherSuites []uint16
+	for _, name := range c.CipherSuites {
+		id, err := cipherSuiteID(name)
+		if err != nil {
+			return nil, err
+		}
+		cipherSuites = append(cipherSuites, id)
+	}
+
+	return &tls.Config{
+		MinVersion:   minVersion,
+		CipherSuites: cipherSuites,
+	}, nil
+}
+
+// cipherSuiteID looks up a secure TLS 1.0-1.2 cipher suite by name.
+func cipherSuiteID(name string) (uint16, error) {
+	for _, suite := range tls.CipherSuites() {
+		if suite.Name != name {
+			continue
+		}
+		if !slices.ContainsFunc(suite.SupportedVersions, func(v uint16) bool { return v < tls.VersionTLS13 }) {
+			return 0, fmt.Errorf("TLS 1.3 cipher suite %q is not configurable", name)
+		}
+		return suite.ID, nil
+	}
+	return 0, fmt.Errorf("unsupported or insecure cipher suite %q", name)
+}
+
+// reloadCertificatesOnSignal reloads the certificates on SIGHUP until ctx is done.
+func reloadCertificatesOnSignal(ctx context.Context, reloaders []*certs.Reloader) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	defer signal.Stop(ch)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ch:
+			for _, r := range reloaders {
+				if err := r.Reload(); err != nil {
+					log.Println("failed to reload certificate:", err)
+				}
+			}
+			log.Println("reloaded certificates")
+		}
+	}
+}
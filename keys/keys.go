@@ -0,0 +1,270 @@
+package keys
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/google/uuid"
+	"github.com/ophum/simpleident/models"
+	"gorm.io/gorm"
+)
+
+const (
+	RS256 = jose.RS256
+	ES256 = jose.ES256
+)
+
+// Algorithms are the signing algorithms the server manages keys for.
+var Algorithms = []jose.SignatureAlgorithm{RS256, ES256}
+
+var (
+	ErrUnsupportedAlgorithm = errors.New("unsupported algorithm")
+	ErrKeyNotFound          = errors.New("key not found")
+)
+
+type Key struct {
+	KeyID      string
+	Algorithm  jose.SignatureAlgorithm
+	PrivateKey crypto.Signer
+	State      models.SigningKeyState
+}
+
+type Config struct {
+	// automatic rotation is disabled if zero
+	RotationInterval time.Duration
+	// how long a pending key is published before it is activated
+	PrepublishPeriod time.Duration
+	// the longest lifetime of tokens signed by the keys, retiring keys stay published for it
+	TokenLifetime time.Duration
+}
+
+// Manager holds the signing keys persisted in the database.
+type Manager struct {
+	db     *gorm.DB
+	config *Config
+
+	mu sync.RWMutex
+	// active keys by algorithm
+	active map[jose.SignatureAlgorithm]*Key
+	// pending, active and retiring keys
+	published []*Key
+}
+
+func NewManager(db *gorm.DB, config *Config) *Manager {
+	return &Manager{
+		db:     db,
+		config: config,
+		active: map[jose.SignatureAlgorithm]*Key{},
+	}
+}
+
+// Init activates a key for each algorithm which has none yet and loads the keys.
+func (m *Manager) Init() error {
+	for _, alg := range Algorithms {
+		var count int64
+		if err := m.db.Model(&models.SigningKey{}).
+			Where("algorithm = ? AND state = ?", string(alg), models.SigningKeyStateActive).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+		if _, err := m.Rotate(alg); err != nil {
+			return err
+		}
+	}
+	return m.Reload()
+}
+
+// Reload loads the keys from the database, so keys rotated by other processes are picked up.
+func (m *Manager) Reload() error {
+	var signingKeys []*models.SigningKey
+	if err := m.db.Where("state IN ?", []models.SigningKeyState{
+		models.SigningKeyStatePending,
+		models.SigningKeyStateActive,
+		models.SigningKeyStateRetiring,
+	}).Order("created_at").Find(&signingKeys).Error; err != nil {
+		return err
+	}
+
+	active := map[jose.SignatureAlgorithm]*Key{}
+	published := []*Key{}
+	for _, signingKey := range signingKeys {
+		key, err := parseSigningKey(signingKey)
+		if err != nil {
+			return err
+		}
+		if key.State == models.SigningKeyStateActive {
+			active[key.Algorithm] = key
+		}
+		published = append(published, key)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.active = active
+	m.published = published
+	return nil
+}
+
+// SigningKey returns the key currently used to sign with alg.
+func (m *Manager) SigningKey(alg jose.SignatureAlgorithm) (*Key, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	key, ok := m.active[alg]
+	if !ok {
+		return nil, ErrUnsupportedAlgorithm
+	}
+	return key, nil
+}
+
+// Key returns the published key identified by kid.
+func (m *Manager) Key(kid string) (*Key, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, key := range m.published {
+		if key.KeyID == kid {
+			return key, nil
+		}
+	}
+	return nil, ErrKeyNotFound
+}
+
+// Sign signs payload with the current key of alg and returns the JWS compact serialization.
+func (m *Manager) Sign(alg jose.SignatureAlgorithm, typ string, payload []byte) (string, error) {
+	key, err := m.SigningKey(alg)
+	if err != nil {
+		return "", err
+	}
+
+	opts := &jose.SignerOptions{}
+	opts.WithType(jose.ContentType(typ))
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: alg,
+		Key: jose.JSONWebKey{
+			Key:       key.PrivateKey,
+			KeyID:     key.KeyID,
+			Algorithm: string(alg),
+		},
+	}, opts)
+	if err != nil {
+		return "", err
+	}
+
+	jws, err := signer.Sign(payload)
+	if err != nil {
+		return "", err
+	}
+	return jws.CompactSerialize()
+}
+
+// PublicKeys returns the public halves of the published keys as a JWK Set.
+func (m *Manager) PublicKeys() *jose.JSONWebKeySet {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	set := &jose.JSONWebKeySet{
+		Keys: []jose.JSONWebKey{},
+	}
+	for _, key := range m.published {
+		set.Keys = append(set.Keys, jose.JSONWebKey{
+			Key:       key.PrivateKey.Public(),
+			KeyID:     key.KeyID,
+			Algorithm: string(key.Algorithm),
+			Use:       "sig",
+		})
+	}
+	return set
+}
+
+// Loaded reports whether a signing key is loaded for every algorithm.
+func (m *Manager) Loaded() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, alg := range Algorithms {
+		if _, ok := m.active[alg]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func create(tx *gorm.DB, alg jose.SignatureAlgorithm, privateKey crypto.Signer, state models.SigningKeyState) (*models.SigningKey, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+
+	signingKey := &models.SigningKey{
+		Model: models.Model{
+			ID: id,
+		},
+		KeyID:     id.String(),
+		Algorithm: string(alg),
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{
+			Type:  "PRIVATE KEY",
+			Bytes: der,
+		})),
+		State: state,
+	}
+	if state == models.SigningKeyStateActive {
+		now := time.Now()
+		signingKey.ActivatedAt = &now
+	}
+	if err := tx.Create(signingKey).Error; err != nil {
+		return nil, err
+	}
+	return signingKey, nil
+}
+
+func generatePrivateKey(alg jose.SignatureAlgorithm) (crypto.Signer, error) {
+	switch alg {
+	case RS256:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case ES256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	}
+	return nil, ErrUnsupportedAlgorithm
+}
+
+func parseSigningKey(signingKey *models.SigningKey) (*Key, error) {
+	block, _ := pem.Decode([]byte(signingKey.PrivateKey))
+	if block == nil {
+		return nil, errors.New("invalid private key")
+	}
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("invalid private key")
+	}
+
+	return &Key{
+		KeyID:      signingKey.KeyID,
+		Algorithm:  jose.SignatureAlgorithm(signingKey.Algorithm),
+		PrivateKey: signer,
+		State:      signingKey.State,
+	}, nil
+}
@@ -0,0 +1,224 @@
+package keys
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/ophum/simpleident/models"
+	"gorm.io/gorm"
+)
+
+var errConcurrentRotation = errors.New("signing key was rotated concurrently")
+
+// Rotate activates the pending key of alg, or a newly generated key if there is none,
+// and retires the current active key once the tokens it signed have expired.
+func (m *Manager) Rotate(alg jose.SignatureAlgorithm) (*models.SigningKey, error) {
+	if !isSupported(alg) {
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	var activated *models.SigningKey
+	if err := m.db.Transaction(func(tx *gorm.DB) error {
+		var pending models.SigningKey
+		err := tx.Where("algorithm = ? AND state = ?", string(alg), models.SigningKeyStatePending).
+			Order("created_at").
+			First(&pending).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			privateKey, err := generatePrivateKey(alg)
+			if err != nil {
+				return err
+			}
+			created, err := create(tx, alg, privateKey, models.SigningKeyStatePending)
+			if err != nil {
+				return err
+			}
+			pending = *created
+		} else if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		retireAt := now.Add(m.config.TokenLifetime)
+		if err := tx.Model(&models.SigningKey{}).
+			Where("algorithm = ? AND state = ?", string(alg), models.SigningKeyStateActive).
+			Updates(map[string]any{
+				"state":     models.SigningKeyStateRetiring,
+				"retire_at": retireAt,
+			}).Error; err != nil {
+			return err
+		}
+
+		// another process may activate the same pending key at the same time.
+		result := tx.Model(&models.SigningKey{}).
+			Where("id = ? AND state = ?", pending.ID, models.SigningKeyStatePending).
+			Updates(map[string]any{
+				"state":        models.SigningKeyStateActive,
+				"activated_at": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errConcurrentRotation
+		}
+
+		pending.State = models.SigningKeyStateActive
+		pending.ActivatedAt = &now
+		activated = &pending
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return activated, m.Reload()
+}
+
+// Prepare generates a pending key of alg unless there already is one,
+// so relying parties can fetch it before it is used for signing.
+func (m *Manager) Prepare(alg jose.SignatureAlgorithm) error {
+	var count int64
+	if err := m.db.Model(&models.SigningKey{}).
+		Where("algorithm = ? AND state = ?", string(alg), models.SigningKeyStatePending).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	privateKey, err := generatePrivateKey(alg)
+	if err != nil {
+		return err
+	}
+	if _, err := create(m.db, alg, privateKey, models.SigningKeyStatePending); err != nil {
+		return err
+	}
+	return m.Reload()
+}
+
+// Import stores a PEM encoded private key as a pending key of alg.
+// PKCS #8, PKCS #1 (RSA) and SEC 1 (EC) encodings are accepted.
+func (m *Manager) Import(alg jose.SignatureAlgorithm, data []byte) (*models.SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	var privateKey any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		privateKey, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		privateKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		if alg != RS256 {
+			return nil, errors.New("RSA key cannot be used with " + string(alg))
+		}
+		if key.N.BitLen() < 2048 {
+			return nil, errors.New("RSA key must be at least 2048 bits")
+		}
+	case *ecdsa.PrivateKey:
+		if alg != ES256 || key.Curve != elliptic.P256() {
+			return nil, errors.New("EC key cannot be used with " + string(alg))
+		}
+	default:
+		return nil, errors.New("unsupported key type")
+	}
+
+	signingKey, err := create(m.db, alg, privateKey.(crypto.Signer), models.SigningKeyStatePending)
+	if err != nil {
+		return nil, err
+	}
+	return signingKey, m.Reload()
+}
+
+// List returns every key including retired ones.
+func (m *Manager) List() ([]*models.SigningKey, error) {
+	var signingKeys []*models.SigningKey
+	if err := m.db.Order("algorithm, created_at").Find(&signingKeys).Error; err != nil {
+		return nil, err
+	}
+	return signingKeys, nil
+}
+
+// Tick runs the scheduled part of the key lifecycle once.
+func (m *Manager) Tick(now time.Time) error {
+	if err := m.db.Model(&models.SigningKey{}).
+		Where("state = ? AND retire_at <= ?", models.SigningKeyStateRetiring, now).
+		Update("state", models.SigningKeyStateRetired).Error; err != nil {
+		return err
+	}
+
+	if m.config.RotationInterval > 0 {
+		prepublish := min(m.config.PrepublishPeriod, m.config.RotationInterval/2)
+		for _, alg := range Algorithms {
+			var active models.SigningKey
+			if err := m.db.Where("algorithm = ? AND state = ?", string(alg), models.SigningKeyStateActive).
+				First(&active).Error; err != nil {
+				return err
+			}
+			if active.ActivatedAt == nil {
+				continue
+			}
+
+			rotateAt := active.ActivatedAt.Add(m.config.RotationInterval)
+			if !now.Before(rotateAt) {
+				if _, err := m.Rotate(alg); err != nil && !errors.Is(err, errConcurrentRotation) {
+					return err
+				}
+				continue
+			}
+			if !now.Before(rotateAt.Add(-prepublish)) {
+				if err := m.Prepare(alg); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return m.Reload()
+}
+
+// Run runs Tick every period until ctx is done.
+func (m *Manager) Run(ctx context.Context, period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := m.Tick(now); err != nil {
+				log.Println("failed to rotate signing keys:", err)
+			}
+		}
+	}
+}
+
+func isSupported(alg jose.SignatureAlgorithm) bool {
+	for _, a := range Algorithms {
+		if a == alg {
+			return true
+		}
+	}
+	return false
+}
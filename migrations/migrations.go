@@ -0,0 +1,202 @@
+// Package migrations applies the embedded schema migrations with sql-migrate.
+package migrations
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"time"
+
+	migrate "github.com/rubenv/sql-migrate"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+//go:embed sqlite3/*.sql postgres/*.sql mysql/*.sql
+var FS embed.FS
+
+// sql-migrate dialects and migration directories by gorm dialector name
+var dialects = map[string]string{
+	"sqlite":   "sqlite3",
+	"postgres": "postgres",
+	"mysql":    "mysql",
+}
+
+func source(db *gorm.DB) (migrate.MigrationSource, string, error) {
+	dialect, ok := dialects[db.Dialector.Name()]
+	if !ok {
+		return nil, "", fmt.Errorf("migrations: unsupported database %s", db.Dialector.Name())
+	}
+	return &migrate.EmbedFileSystemMigrationSource{
+		FileSystem: FS,
+		Root:       dialect,
+	}, dialect, nil
+}
+
+// Up applies at most max pending migrations, all of them if max is 0.
+func Up(db *gorm.DB, max int) (int, error) {
+	return exec(db, migrate.Up, max)
+}
+
+// Down rolls back at most max applied migrations, all of them if max is 0.
+func Down(db *gorm.DB, max int) (int, error) {
+	return exec(db, migrate.Down, max)
+}
+
+func exec(db *gorm.DB, dir migrate.MigrationDirection, max int) (int, error) {
+	src, dialect, err := source(db)
+	if err != nil {
+		return 0, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return 0, err
+	}
+	return migrate.ExecMax(sqlDB, dialect, src, dir, max)
+}
+
+type Status struct {
+	ID string
+	// nil if pending
+	AppliedAt *time.Time
+}
+
+// List returns every known migration in order, and whether it has been applied.
+func List(db *gorm.DB) ([]*Status, error) {
+	src, dialect, err := source(db)
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	migrations, err := src.FindMigrations()
+	if err != nil {
+		return nil, err
+	}
+	records, err := migrate.GetMigrationRecords(sqlDB, dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := map[string]time.Time{}
+	for _, r := range records {
+		applied[r.Id] = r.AppliedAt
+	}
+
+	statuses := []*Status{}
+	for _, m := range migrations {
+		status := &Status{ID: m.Id}
+		if t, ok := applied[m.Id]; ok {
+			status.AppliedAt = &t
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+const (
+	lockTable = "simpleident_migration_lock"
+	// a lock older than this is considered abandoned by a crashed process
+	lockTTL = time.Minute * 10
+	// how often a held lock is retried
+	lockRetryInterval = time.Second
+	// how often the lock is extended while migrations run
+	lockRenewInterval = lockTTL / 3
+)
+
+type migrationLock struct {
+	ID        int
+	Owner     string
+	ExpiresAt time.Time
+}
+
+func (migrationLock) TableName() string {
+	return lockTable
+}
+
+// Lock serializes migrations between processes sharing the database,
+// waiting until the lock is acquired or ctx is done.
+// The lock is extended until unlock is called, however long the migrations take.
+func Lock(ctx context.Context, db *gorm.DB, owner string) (unlock func() error, err error) {
+	if err := db.Exec("CREATE TABLE IF NOT EXISTS " + lockTable + " (" +
+		"id INTEGER PRIMARY KEY, " +
+		"owner VARCHAR(255), " +
+		"expires_at TIMESTAMP" +
+		")").Error; err != nil {
+		return nil, err
+	}
+
+	lockDB := db.WithContext(ctx)
+	for {
+		if err := lockDB.Where("id = 1 AND expires_at < ?", time.Now()).
+			Delete(&migrationLock{}).Error; err != nil {
+			return nil, err
+		}
+
+		// only one process can insert the row, a conflict while it is held is expected and not logged
+		err := lockDB.Session(&gorm.Session{Logger: db.Logger.LogMode(logger.Silent)}).Create(&migrationLock{
+			ID:        1,
+			Owner:     owner,
+			ExpiresAt: time.Now().Add(lockTTL),
+		}).Error
+		if err == nil {
+			break
+		}
+		if !isDuplicatedKey(db, err) {
+			return nil, fmt.Errorf("migrations: failed to acquire the lock: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("migrations: failed to acquire the lock: %w", ctx.Err())
+		case <-time.After(lockRetryInterval):
+		}
+	}
+
+	// ctx only bounds the wait, the lock is held until unlock.
+	db = db.WithContext(context.Background())
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(lockRenewInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			result := db.Model(&migrationLock{}).
+				Where("id = 1 AND owner = ?", owner).
+				Update("expires_at", time.Now().Add(lockTTL))
+			if result.Error != nil {
+				db.Logger.Error(context.Background(), "migrations: failed to renew the lock: %v", result.Error)
+			} else if result.RowsAffected == 0 {
+				db.Logger.Error(context.Background(), "migrations: the lock of %s has been taken over", owner)
+			}
+		}
+	}()
+
+	return func() error {
+		close(done)
+		<-stopped
+		return db.Where("id = 1 AND owner = ?", owner).
+			Delete(&migrationLock{}).Error
+	}, nil
+}
+
+// isDuplicatedKey reports whether err is a unique constraint violation, which is how a held lock is detected.
+// Other errors, such as a missing table or privilege, are not retried.
+func isDuplicatedKey(db *gorm.DB, err error) bool {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return true
+	}
+	translator, ok := db.Dialector.(gorm.ErrorTranslator)
+	return ok && errors.Is(translator.Translate(err), gorm.ErrDuplicatedKey)
+}
@@ -0,0 +1,59 @@
+
+-- +migrate Up
+CREATE TABLE `accounts` (
+    id VARCHAR(36) PRIMARY KEY,
+    username VARCHAR(255),
+    password TEXT,
+    created_at DATETIME(3),
+    updated_at DATETIME(3),
+    deleted_at DATETIME(3)
+);
+
+CREATE TABLE `oauth2_clients` (
+    id VARCHAR(36) PRIMARY KEY,
+    name TEXT,
+    description TEXT,
+    callback_url TEXT,
+    created_at DATETIME(3),
+    updated_at DATETIME(3),
+    deleted_at DATETIME(3)
+);
+
+CREATE TABLE `oauth2_client_secrets` (
+    id VARCHAR(36) PRIMARY KEY,
+    -- TODO foreign key
+    oauth2_client_id VARCHAR(36),
+    secret VARCHAR(255),
+    created_at DATETIME(3),
+    updated_at DATETIME(3),
+    deleted_at DATETIME(3)
+);
+
+CREATE TABLE `oauth2_codes` (
+    id VARCHAR(36) PRIMARY KEY,
+    oauth2_client_id VARCHAR(36),
+    -- compared case-sensitively
+    code VARBINARY(255),
+    account_id VARCHAR(36),
+    created_at DATETIME(3),
+    updated_at DATETIME(3),
+    deleted_at DATETIME(3)
+);
+
+CREATE TABLE `oauth2_tokens` (
+    id VARCHAR(36) PRIMARY KEY,
+    oauth2_client_id VARCHAR(36),
+    -- compared case-sensitively, JWT access tokens are stored as they are
+    token VARBINARY(3072),
+    account_id VARCHAR(36),
+    created_at DATETIME(3),
+    updated_at DATETIME(3),
+    deleted_at DATETIME(3)
+);
+
+-- +migrate Down
+DROP TABLE `oauth2_tokens`;
+DROP TABLE `oauth2_codes`;
+DROP TABLE `oauth2_client_secrets`;
+DROP TABLE `oauth2_clients`;
+DROP TABLE `accounts`;
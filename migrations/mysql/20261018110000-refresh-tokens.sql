@@ -0,0 +1,25 @@
+
+-- +migrate Up
+ALTER TABLE `oauth2_tokens` ADD COLUMN family_id VARCHAR(36);
+ALTER TABLE `oauth2_tokens` ADD COLUMN expires_at DATETIME(3);
+UPDATE `oauth2_tokens` SET expires_at = created_at;
+
+CREATE TABLE `oauth2_refresh_tokens` (
+    id VARCHAR(36) PRIMARY KEY,
+    oauth2_client_id VARCHAR(36),
+    token VARBINARY(255),
+    account_id VARCHAR(36),
+    family_id VARCHAR(36),
+    expires_at DATETIME(3),
+    used_at DATETIME(3),
+    created_at DATETIME(3),
+    updated_at DATETIME(3),
+    deleted_at DATETIME(3)
+);
+CREATE UNIQUE INDEX `idx_oauth2_refresh_tokens_token` ON `oauth2_refresh_tokens` (token);
+CREATE INDEX `idx_oauth2_refresh_tokens_family_id` ON `oauth2_refresh_tokens` (family_id);
+
+-- +migrate Down
+DROP TABLE `oauth2_refresh_tokens`;
+ALTER TABLE `oauth2_tokens` DROP COLUMN expires_at;
+ALTER TABLE `oauth2_tokens` DROP COLUMN family_id;
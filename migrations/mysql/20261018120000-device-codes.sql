@@ -0,0 +1,21 @@
+
+-- +migrate Up
+CREATE TABLE `oauth2_device_codes` (
+    id VARCHAR(36) PRIMARY KEY,
+    oauth2_client_id VARCHAR(36),
+    device_code VARBINARY(255),
+    user_code VARCHAR(255),
+    status VARCHAR(16),
+    account_id VARCHAR(36),
+    polling_interval INTEGER,
+    last_polled_at DATETIME(3),
+    expires_at DATETIME(3),
+    created_at DATETIME(3),
+    updated_at DATETIME(3),
+    deleted_at DATETIME(3)
+);
+CREATE UNIQUE INDEX `idx_oauth2_device_codes_device_code` ON `oauth2_device_codes` (device_code);
+CREATE INDEX `idx_oauth2_device_codes_user_code` ON `oauth2_device_codes` (user_code);
+
+-- +migrate Down
+DROP TABLE `oauth2_device_codes`;
@@ -0,0 +1,22 @@
+
+-- +migrate Up
+CREATE TABLE `signing_keys` (
+    id VARCHAR(36) PRIMARY KEY,
+    key_id VARCHAR(255),
+    algorithm VARCHAR(16),
+    private_key TEXT,
+    created_at DATETIME(3),
+    updated_at DATETIME(3),
+    deleted_at DATETIME(3)
+);
+CREATE UNIQUE INDEX `idx_signing_keys_key_id` ON `signing_keys` (key_id);
+
+ALTER TABLE `oauth2_clients` ADD COLUMN id_token_signing_alg VARCHAR(16) NOT NULL DEFAULT '';
+ALTER TABLE `oauth2_codes` ADD COLUMN nonce VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE `oauth2_codes` ADD COLUMN auth_time DATETIME(3);
+
+-- +migrate Down
+ALTER TABLE `oauth2_codes` DROP COLUMN auth_time;
+ALTER TABLE `oauth2_codes` DROP COLUMN nonce;
+ALTER TABLE `oauth2_clients` DROP COLUMN id_token_signing_alg;
+DROP TABLE `signing_keys`;
@@ -0,0 +1,12 @@
+
+-- +migrate Up
+ALTER TABLE `signing_keys` ADD COLUMN state VARCHAR(16) NOT NULL DEFAULT 'pending';
+ALTER TABLE `signing_keys` ADD COLUMN activated_at DATETIME(3);
+ALTER TABLE `signing_keys` ADD COLUMN retire_at DATETIME(3);
+-- keys created before the lifecycle existed are the active ones
+UPDATE `signing_keys` SET state = 'active', activated_at = created_at;
+
+-- +migrate Down
+ALTER TABLE `signing_keys` DROP COLUMN retire_at;
+ALTER TABLE `signing_keys` DROP COLUMN activated_at;
+ALTER TABLE `signing_keys` DROP COLUMN state;
@@ -0,0 +1,28 @@
+
+-- +migrate Up
+CREATE TABLE `oauth2_client_redirect_uris` (
+    id VARCHAR(36) PRIMARY KEY,
+    oauth2_client_id VARCHAR(36),
+    uri TEXT,
+    created_at DATETIME(3),
+    updated_at DATETIME(3),
+    deleted_at DATETIME(3)
+);
+CREATE INDEX `idx_oauth2_client_redirect_uris_oauth2_client_id` ON `oauth2_client_redirect_uris` (oauth2_client_id);
+-- callback_url was matched as a prefix, register it as an exact redirect URI
+INSERT INTO `oauth2_client_redirect_uris` (id, oauth2_client_id, uri, created_at, updated_at)
+    SELECT UUID(), id, callback_url, created_at, updated_at
+    FROM `oauth2_clients`
+    WHERE callback_url IS NOT NULL AND callback_url <> '';
+ALTER TABLE `oauth2_clients` DROP COLUMN callback_url;
+ALTER TABLE `oauth2_codes` ADD COLUMN redirect_uri VARCHAR(2048) NOT NULL DEFAULT '';
+
+-- +migrate Down
+ALTER TABLE `oauth2_codes` DROP COLUMN redirect_uri;
+ALTER TABLE `oauth2_clients` ADD COLUMN callback_url TEXT;
+UPDATE `oauth2_clients` SET callback_url = (
+    SELECT uri FROM `oauth2_client_redirect_uris`
+    WHERE oauth2_client_id = `oauth2_clients`.id AND deleted_at IS NULL
+    ORDER BY created_at LIMIT 1
+);
+DROP TABLE `oauth2_client_redirect_uris`;
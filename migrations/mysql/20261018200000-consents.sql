@@ -0,0 +1,19 @@
+
+-- +migrate Up
+ALTER TABLE `oauth2_clients` ADD COLUMN trusted BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE `oauth2_consents` (
+    id VARCHAR(36) PRIMARY KEY,
+    account_id VARCHAR(36),
+    oauth2_client_id VARCHAR(36),
+    scope VARCHAR(1024) NOT NULL DEFAULT '',
+    granted_at DATETIME(3),
+    created_at DATETIME(3),
+    updated_at DATETIME(3),
+    deleted_at DATETIME(3)
+);
+CREATE INDEX `idx_oauth2_consents_account_id_oauth2_client_id` ON `oauth2_consents` (account_id, oauth2_client_id);
+
+-- +migrate Down
+DROP TABLE `oauth2_consents`;
+ALTER TABLE `oauth2_clients` DROP COLUMN trusted;
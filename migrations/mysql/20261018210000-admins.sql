@@ -0,0 +1,6 @@
+
+-- +migrate Up
+ALTER TABLE `accounts` ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +migrate Down
+ALTER TABLE `accounts` DROP COLUMN is_admin;
@@ -0,0 +1,6 @@
+
+-- +migrate Up
+ALTER TABLE `oauth2_clients` ADD COLUMN audiences VARCHAR(1024) NOT NULL DEFAULT '';
+
+-- +migrate Down
+ALTER TABLE `oauth2_clients` DROP COLUMN audiences;
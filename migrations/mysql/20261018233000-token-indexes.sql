@@ -0,0 +1,9 @@
+
+-- +migrate Up
+-- access tokens are looked up on every API request, codes on every token request
+CREATE INDEX `idx_oauth2_tokens_token` ON `oauth2_tokens` (token);
+CREATE INDEX `idx_oauth2_codes_code` ON `oauth2_codes` (code);
+
+-- +migrate Down
+DROP INDEX `idx_oauth2_codes_code` ON `oauth2_codes`;
+DROP INDEX `idx_oauth2_tokens_token` ON `oauth2_tokens`;
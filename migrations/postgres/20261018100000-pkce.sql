@@ -0,0 +1,10 @@
+
+-- +migrate Up
+ALTER TABLE oauth2_clients ADD COLUMN require_pkce BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE oauth2_codes ADD COLUMN code_challenge TEXT;
+ALTER TABLE oauth2_codes ADD COLUMN code_challenge_method TEXT;
+
+-- +migrate Down
+ALTER TABLE oauth2_codes DROP COLUMN code_challenge_method;
+ALTER TABLE oauth2_codes DROP COLUMN code_challenge;
+ALTER TABLE oauth2_clients DROP COLUMN require_pkce;
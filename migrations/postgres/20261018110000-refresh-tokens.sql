@@ -0,0 +1,25 @@
+
+-- +migrate Up
+ALTER TABLE oauth2_tokens ADD COLUMN family_id TEXT;
+ALTER TABLE oauth2_tokens ADD COLUMN expires_at TIMESTAMPTZ;
+UPDATE oauth2_tokens SET expires_at = created_at;
+
+CREATE TABLE oauth2_refresh_tokens (
+    id TEXT PRIMARY KEY,
+    oauth2_client_id TEXT,
+    token TEXT,
+    account_id TEXT,
+    family_id TEXT,
+    expires_at TIMESTAMPTZ,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX idx_oauth2_refresh_tokens_token ON oauth2_refresh_tokens (token);
+CREATE INDEX idx_oauth2_refresh_tokens_family_id ON oauth2_refresh_tokens (family_id);
+
+-- +migrate Down
+DROP TABLE oauth2_refresh_tokens;
+ALTER TABLE oauth2_tokens DROP COLUMN expires_at;
+ALTER TABLE oauth2_tokens DROP COLUMN family_id;
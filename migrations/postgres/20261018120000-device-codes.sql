@@ -0,0 +1,21 @@
+
+-- +migrate Up
+CREATE TABLE oauth2_device_codes (
+    id TEXT PRIMARY KEY,
+    oauth2_client_id TEXT,
+    device_code TEXT,
+    user_code TEXT,
+    status TEXT,
+    account_id TEXT,
+    polling_interval INTEGER,
+    last_polled_at TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX idx_oauth2_device_codes_device_code ON oauth2_device_codes (device_code);
+CREATE INDEX idx_oauth2_device_codes_user_code ON oauth2_device_codes (user_code);
+
+-- +migrate Down
+DROP TABLE oauth2_device_codes;
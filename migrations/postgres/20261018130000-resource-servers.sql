@@ -0,0 +1,6 @@
+
+-- +migrate Up
+ALTER TABLE oauth2_clients ADD COLUMN is_resource_server BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +migrate Down
+ALTER TABLE oauth2_clients DROP COLUMN is_resource_server;
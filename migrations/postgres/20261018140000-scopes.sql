@@ -0,0 +1,19 @@
+
+-- +migrate Up
+ALTER TABLE oauth2_clients ADD COLUMN allowed_scopes TEXT NOT NULL DEFAULT '';
+ALTER TABLE oauth2_codes ADD COLUMN scope TEXT NOT NULL DEFAULT '';
+ALTER TABLE oauth2_tokens ADD COLUMN scope TEXT NOT NULL DEFAULT '';
+ALTER TABLE oauth2_refresh_tokens ADD COLUMN scope TEXT NOT NULL DEFAULT '';
+ALTER TABLE oauth2_device_codes ADD COLUMN scope TEXT NOT NULL DEFAULT '';
+-- existing clients and tokens keep access to /api/userinfo
+UPDATE oauth2_clients SET allowed_scopes = 'openid profile';
+UPDATE oauth2_codes SET scope = 'openid profile';
+UPDATE oauth2_tokens SET scope = 'openid profile' WHERE account_id IS NOT NULL;
+UPDATE oauth2_refresh_tokens SET scope = 'openid profile';
+
+-- +migrate Down
+ALTER TABLE oauth2_device_codes DROP COLUMN scope;
+ALTER TABLE oauth2_refresh_tokens DROP COLUMN scope;
+ALTER TABLE oauth2_tokens DROP COLUMN scope;
+ALTER TABLE oauth2_codes DROP COLUMN scope;
+ALTER TABLE oauth2_clients DROP COLUMN allowed_scopes;
@@ -0,0 +1,22 @@
+
+-- +migrate Up
+CREATE TABLE signing_keys (
+    id TEXT PRIMARY KEY,
+    key_id TEXT,
+    algorithm TEXT,
+    private_key TEXT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX idx_signing_keys_key_id ON signing_keys (key_id);
+
+ALTER TABLE oauth2_clients ADD COLUMN id_token_signing_alg TEXT NOT NULL DEFAULT '';
+ALTER TABLE oauth2_codes ADD COLUMN nonce TEXT NOT NULL DEFAULT '';
+ALTER TABLE oauth2_codes ADD COLUMN auth_time TIMESTAMPTZ;
+
+-- +migrate Down
+ALTER TABLE oauth2_codes DROP COLUMN auth_time;
+ALTER TABLE oauth2_codes DROP COLUMN nonce;
+ALTER TABLE oauth2_clients DROP COLUMN id_token_signing_alg;
+DROP TABLE signing_keys;
@@ -0,0 +1,12 @@
+
+-- +migrate Up
+ALTER TABLE signing_keys ADD COLUMN state TEXT NOT NULL DEFAULT 'pending';
+ALTER TABLE signing_keys ADD COLUMN activated_at TIMESTAMPTZ;
+ALTER TABLE signing_keys ADD COLUMN retire_at TIMESTAMPTZ;
+-- keys created before the lifecycle existed are the active ones
+UPDATE signing_keys SET state = 'active', activated_at = created_at;
+
+-- +migrate Down
+ALTER TABLE signing_keys DROP COLUMN retire_at;
+ALTER TABLE signing_keys DROP COLUMN activated_at;
+ALTER TABLE signing_keys DROP COLUMN state;
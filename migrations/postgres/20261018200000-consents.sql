@@ -0,0 +1,19 @@
+
+-- +migrate Up
+ALTER TABLE oauth2_clients ADD COLUMN trusted BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE oauth2_consents (
+    id TEXT PRIMARY KEY,
+    account_id TEXT,
+    oauth2_client_id TEXT,
+    scope TEXT NOT NULL DEFAULT '',
+    granted_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ
+);
+CREATE INDEX idx_oauth2_consents_account_id_oauth2_client_id ON oauth2_consents (account_id, oauth2_client_id);
+
+-- +migrate Down
+DROP TABLE oauth2_consents;
+ALTER TABLE oauth2_clients DROP COLUMN trusted;
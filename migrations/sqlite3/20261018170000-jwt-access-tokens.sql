@@ -0,0 +1,6 @@
+
+-- +migrate Up
+ALTER TABLE `oauth2_clients` ADD COLUMN access_token_format TEXT NOT NULL DEFAULT 'opaque';
+
+-- +migrate Down
+ALTER TABLE `oauth2_clients` DROP COLUMN access_token_format;
@@ -0,0 +1,31 @@
+
+-- +migrate Up
+CREATE TABLE `oauth2_client_redirect_uris` (
+    id TEXT PRIMARY KEY,
+    oauth2_client_id TEXT,
+    uri TEXT,
+    created_at DATETIME,
+    updated_at DATETIME,
+    deleted_at DATETIME
+);
+CREATE INDEX `idx_oauth2_client_redirect_uris_oauth2_client_id` ON `oauth2_client_redirect_uris` (oauth2_client_id);
+-- callback_url was matched as a prefix, register it as an exact redirect URI
+INSERT INTO `oauth2_client_redirect_uris` (id, oauth2_client_id, uri, created_at, updated_at)
+    SELECT
+        lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' ||
+            substr('89ab', 1 + (abs(random()) % 4), 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6))),
+        id, callback_url, created_at, updated_at
+    FROM `oauth2_clients`
+    WHERE callback_url IS NOT NULL AND callback_url <> '';
+ALTER TABLE `oauth2_clients` DROP COLUMN callback_url;
+ALTER TABLE `oauth2_codes` ADD COLUMN redirect_uri TEXT NOT NULL DEFAULT '';
+
+-- +migrate Down
+ALTER TABLE `oauth2_codes` DROP COLUMN redirect_uri;
+ALTER TABLE `oauth2_clients` ADD COLUMN callback_url TEXT;
+UPDATE `oauth2_clients` SET callback_url = (
+    SELECT uri FROM `oauth2_client_redirect_uris`
+    WHERE oauth2_client_id = `oauth2_clients`.id AND deleted_at IS NULL
+    ORDER BY created_at LIMIT 1
+);
+DROP TABLE `oauth2_client_redirect_uris`;
@@ -0,0 +1,8 @@
+
+-- +migrate Up
+ALTER TABLE `oauth2_codes` ADD COLUMN family_id TEXT;
+ALTER TABLE `oauth2_codes` ADD COLUMN used_at DATETIME;
+
+-- +migrate Down
+ALTER TABLE `oauth2_codes` DROP COLUMN used_at;
+ALTER TABLE `oauth2_codes` DROP COLUMN family_id;
@@ -0,0 +1,41 @@
+
+-- +migrate Up
+CREATE TABLE `roles` (
+    id TEXT PRIMARY KEY,
+    name TEXT,
+    description TEXT,
+    permissions TEXT NOT NULL DEFAULT '',
+    created_at DATETIME,
+    updated_at DATETIME,
+    deleted_at DATETIME
+);
+CREATE UNIQUE INDEX `idx_roles_name` ON `roles` (name);
+
+CREATE TABLE `account_roles` (
+    account_id TEXT,
+    role_id TEXT,
+    PRIMARY KEY (account_id, role_id)
+);
+
+-- administrators become members of the built-in admin role
+INSERT INTO `roles` (id, name, description, permissions, created_at, updated_at)
+    VALUES (
+        'be359b48-9298-4897-a813-e8014497fbaf',
+        'admin',
+        'all permissions',
+        'accounts:read accounts:write clients:read clients:write secrets:generate roles:write',
+        CURRENT_TIMESTAMP,
+        CURRENT_TIMESTAMP
+    );
+INSERT INTO `account_roles` (account_id, role_id)
+    SELECT id, 'be359b48-9298-4897-a813-e8014497fbaf' FROM `accounts` WHERE is_admin;
+ALTER TABLE `accounts` DROP COLUMN is_admin;
+
+-- +migrate Down
+ALTER TABLE `accounts` ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE `accounts` SET is_admin = TRUE WHERE id IN (
+    SELECT account_id FROM `account_roles`
+    WHERE role_id = (SELECT id FROM `roles` WHERE name = 'admin')
+);
+DROP TABLE `account_roles`;
+DROP TABLE `roles`;
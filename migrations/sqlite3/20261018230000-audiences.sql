@@ -0,0 +1,6 @@
+
+-- +migrate Up
+ALTER TABLE `oauth2_clients` ADD COLUMN audiences TEXT NOT NULL DEFAULT '';
+
+-- +migrate Down
+ALTER TABLE `oauth2_clients` DROP COLUMN audiences;
@@ -18,4 +18,6 @@ type Account struct {
 	Model
 	Username string
 	Password string
+	// many to many
+	Roles []*Role `gorm:"many2many:account_roles"`
 }
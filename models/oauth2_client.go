@@ -1,16 +1,41 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type Oauth2Client struct {
 	Model
 	Name        string
 	Description string
-	CallbackURL string
+	// space-delimited scopes the client may request
+	AllowedScopes string
+	RequirePKCE   bool
+	// RS256 if empty
+	IDTokenSigningAlg string
+	AccessTokenFormat Oauth2AccessTokenFormat
+	// space-delimited resource identifiers the JWT access tokens may be issued for (RFC 8707),
+	// the first one is the audience if the token request has no resource parameter.
+	Audiences string
+	// resource servers are allowed to introspect tokens
+	IsResourceServer bool
+	// trusted clients are authorized without asking the user for consent
+	Trusted bool
 	// has many
 	ClientSecrets []*Oauth2ClientSecret
+	RedirectURIs  []*Oauth2ClientRedirectURI
 }
 
+type Oauth2AccessTokenFormat string
+
+const (
+	Oauth2AccessTokenFormatOpaque Oauth2AccessTokenFormat = "opaque"
+	// JWT Profile for OAuth 2.0 Access Tokens (RFC 9068)
+	Oauth2AccessTokenFormatJWT Oauth2AccessTokenFormat = "jwt"
+)
+
 type Oauth2ClientSecret struct {
 	Model
 	// belongs to
@@ -20,17 +45,87 @@ type Oauth2ClientSecret struct {
 	Secret string
 }
 
+type Oauth2ClientRedirectURI struct {
+	Model
+	// belongs to
+	Oauth2ClientID uuid.UUID
+	Oauth2Client   *Oauth2Client
+
+	URI string
+}
+
+// Oauth2Consent is the scope an account has granted to a client.
+type Oauth2Consent struct {
+	Model
+	AccountID      uuid.UUID
+	Oauth2ClientID uuid.UUID
+	Oauth2Client   *Oauth2Client
+	Scope          string
+	GrantedAt      time.Time
+}
+
 type Oauth2Code struct {
 	Model
 	Oauth2ClientID uuid.UUID
 	Code           string
 	AccountID      uuid.UUID
+	Scope          string
+	Nonce          string
+	// when the account signed in, zero if unknown
+	AuthTime time.Time
+	// redirect_uri of the authorization request, empty if it was omitted
+	RedirectURI string
+	// tokens issued from the code belong to the family
+	FamilyID uuid.UUID
+	UsedAt   *time.Time
+
+	CodeChallenge       string
+	CodeChallengeMethod string
 }
 
 type Oauth2Token struct {
+	Model
+	Oauth2ClientID uuid.UUID
+	Token          string
+	// nil if the token is issued to the client itself (client_credentials grant)
+	AccountID *uuid.UUID
+	Account   *Account
+	FamilyID  uuid.UUID
+	Scope     string
+	ExpiresAt time.Time
+}
+
+type Oauth2RefreshToken struct {
 	Model
 	Oauth2ClientID uuid.UUID
 	Token          string
 	AccountID      uuid.UUID
-	Account        *Account
+	// tokens issued from the same authorization share the family
+	FamilyID  uuid.UUID
+	Scope     string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+type Oauth2DeviceCodeStatus string
+
+const (
+	Oauth2DeviceCodeStatusPending  Oauth2DeviceCodeStatus = "pending"
+	Oauth2DeviceCodeStatusApproved Oauth2DeviceCodeStatus = "approved"
+	Oauth2DeviceCodeStatusDenied   Oauth2DeviceCodeStatus = "denied"
+)
+
+type Oauth2DeviceCode struct {
+	Model
+	Oauth2ClientID uuid.UUID
+	DeviceCode     string
+	UserCode       string
+	Status         Oauth2DeviceCodeStatus
+	Scope          string
+	// set when the user approves or denies the request
+	AccountID *uuid.UUID
+	// polling interval in seconds
+	PollingInterval int
+	LastPolledAt    *time.Time
+	ExpiresAt       time.Time
 }
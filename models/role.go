@@ -0,0 +1,32 @@
+package models
+
+type Permission string
+
+const (
+	PermissionAccountsRead    Permission = "accounts:read"
+	PermissionAccountsWrite   Permission = "accounts:write"
+	PermissionClientsRead     Permission = "clients:read"
+	PermissionClientsWrite    Permission = "clients:write"
+	PermissionSecretsGenerate Permission = "secrets:generate"
+	PermissionRolesWrite      Permission = "roles:write"
+)
+
+var Permissions = []Permission{
+	PermissionAccountsRead,
+	PermissionAccountsWrite,
+	PermissionClientsRead,
+	PermissionClientsWrite,
+	PermissionSecretsGenerate,
+	PermissionRolesWrite,
+}
+
+// RoleNameAdmin is the built-in role granted by the admin create command.
+const RoleNameAdmin = "admin"
+
+type Role struct {
+	Model
+	Name        string
+	Description string
+	// space-delimited permissions
+	Permissions string
+}
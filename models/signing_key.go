@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+type SigningKeyState string
+
+const (
+	// published in JWKS ahead of activation, not used for signing yet
+	SigningKeyStatePending SigningKeyState = "pending"
+	// used for signing
+	SigningKeyStateActive SigningKeyState = "active"
+	// no longer used for signing, published until the tokens it signed have expired
+	SigningKeyStateRetiring SigningKeyState = "retiring"
+	// neither used nor published
+	SigningKeyStateRetired SigningKeyState = "retired"
+)
+
+type SigningKey struct {
+	Model
+	// kid of the JWS header
+	KeyID     string
+	Algorithm string
+	// PKCS #8 PEM encoded private key
+	PrivateKey  string
+	State       SigningKeyState
+	ActivatedAt *time.Time
+	// when a retiring key becomes retired
+	RetireAt *time.Time
+}
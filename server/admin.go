@@ -12,21 +12,49 @@ import (
 
 func (s *Server) registerAdminRoutes(router gin.IRouter) {
 	r := router.Group("/admin")
+	r.Use(s.adminAuthMiddleware())
 
-	r.GET("/accounts", handler(s.adminAccountList))
-	r.GET("/accounts/new", handler(s.adminAccountNew))
-	r.POST("/accounts/new", handler(s.adminAccountCreate))
+	accountsRead := requirePermission(models.PermissionAccountsRead)
+	accountsWrite := requirePermission(models.PermissionAccountsWrite)
+	clientsRead := requirePermission(models.PermissionClientsRead)
+	clientsWrite := requirePermission(models.PermissionClientsWrite)
+	secretsGenerate := requirePermission(models.PermissionSecretsGenerate)
+	rolesWrite := requirePermission(models.PermissionRolesWrite)
 
-	r.GET("/oauth2/clients", handler(s.adminOauth2ClientList))
-	r.GET("/oauth2/clients/new", handler(s.adminOauth2ClientNew))
-	r.POST("/oauth2/clients/new", handler(s.adminOauth2ClientCreate))
-	r.GET("/oauth2/clients/:id", handler(s.adminOauth2ClientDetail))
-	r.POST("/oauth2/clients/:id/generate-secret", handler(s.adminOauth2ClientGenerateSecret))
+	r.GET("/accounts", accountsRead, handler(s.adminAccountList))
+	r.GET("/accounts/new", accountsWrite, handler(s.adminAccountNew))
+	r.POST("/accounts/new", accountsWrite, handler(s.adminAccountCreate))
+	r.GET("/accounts/:id", accountsRead, handler(s.adminAccountDetail))
+	r.POST("/accounts/:id/password", accountsWrite, handler(s.adminAccountResetPassword))
+	r.POST("/accounts/:id/roles", rolesWrite, handler(s.adminAccountUpdateRoles))
+
+	r.GET("/roles", rolesWrite, handler(s.adminRoleList))
+	r.GET("/roles/new", rolesWrite, handler(s.adminRoleNew))
+	r.POST("/roles/new", rolesWrite, handler(s.adminRoleCreate))
+	r.GET("/roles/:id", rolesWrite, handler(s.adminRoleDetail))
+	r.POST("/roles/:id/permissions", rolesWrite, handler(s.adminRoleUpdatePermissions))
+
+	r.GET("/oauth2/clients", clientsRead, handler(s.adminOauth2ClientList))
+	r.GET("/oauth2/clients/new", clientsWrite, handler(s.adminOauth2ClientNew))
+	r.POST("/oauth2/clients/new", clientsWrite, handler(s.adminOauth2ClientCreate))
+	r.GET("/oauth2/clients/:id", clientsRead, handler(s.adminOauth2ClientDetail))
+	r.POST("/oauth2/clients/:id/generate-secret", secretsGenerate, handler(s.adminOauth2ClientGenerateSecret))
+	r.POST("/oauth2/clients/:id/scopes", clientsWrite, handler(s.adminOauth2ClientUpdateScopes))
+	r.POST("/oauth2/clients/:id/settings", clientsWrite, handler(s.adminOauth2ClientUpdateSettings))
+	r.POST("/oauth2/clients/:id/redirect-uris", clientsWrite, handler(s.adminOauth2ClientAddRedirectURI))
+	r.POST("/oauth2/clients/:id/redirect-uris/:redirectURIID/delete", clientsWrite, handler(s.adminOauth2ClientDeleteRedirectURI))
+}
+
+func (s *Server) adminIndex(ctx *gin.Context) error {
+	ctx.HTML(http.StatusOK, "admin/index", gin.H{
+		"CSRFToken": csrf.GetToken(ctx),
+	})
+	return nil
 }
 
 func (s *Server) adminAccountList(ctx *gin.Context) error {
 	var accounts []*models.Account
-	if err := s.db.Find(&accounts).Error; err != nil {
+	if err := s.db.Preload("Roles").Find(&accounts).Error; err != nil {
 		return err
 	}
 
@@ -51,8 +79,21 @@ type AdminAccountCreateRequest struct {
 func (s *Server) adminAccountCreate(ctx *gin.Context) error {
 	var req AdminAccountCreateRequest
 	if err := ctx.ShouldBind(&req); err != nil {
+		return newValidationError(err.Error())
+	}
+
+	// sign-in looks accounts up by username, a duplicate could shadow an existing administrator.
+	// New accounts have no roles, so they never hold permissions beyond the administrator's own.
+	var count int64
+	if err := s.db.Model(&models.Account{}).
+		Where("username = ?", req.Username).
+		Count(&count).Error; err != nil {
 		return err
 	}
+	if count > 0 {
+		adminForbidden(ctx, "username already taken")
+		return nil
+	}
 
 	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -76,3 +117,127 @@ func (s *Server) adminAccountCreate(ctx *gin.Context) error {
 	ctx.Redirect(http.StatusSeeOther, "/admin/accounts")
 	return nil
 }
+
+func (s *Server) adminAccountDetail(ctx *gin.Context) error {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return newValidationError("invalid id")
+	}
+
+	var account models.Account
+	if err := s.db.Preload("Roles").
+		Where("id = ?", id).
+		First(&account).Error; err != nil {
+		return err
+	}
+
+	var roles []*models.Role
+	if err := s.db.Order("name").Find(&roles).Error; err != nil {
+		return err
+	}
+
+	assigned := map[uuid.UUID]bool{}
+	for _, role := range account.Roles {
+		assigned[role.ID] = true
+	}
+
+	manageable := hasPermissions(ctx, accountPermissions(&account))
+
+	ctx.HTML(http.StatusOK, "admin/account-detail", gin.H{
+		"Account":       account,
+		"Roles":         roles,
+		"AssignedRoles": assigned,
+		"CanWrite":      manageable && hasPermission(ctx, models.PermissionAccountsWrite),
+		"CanWriteRoles": manageable && hasPermission(ctx, models.PermissionRolesWrite),
+		"CSRFToken":     csrf.GetToken(ctx),
+	})
+	return nil
+}
+
+type AdminAccountResetPasswordRequest struct {
+	Password string `form:"password"`
+}
+
+func (s *Server) adminAccountResetPassword(ctx *gin.Context) error {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return newValidationError("invalid id")
+	}
+
+	var req AdminAccountResetPasswordRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		return newValidationError(err.Error())
+	}
+	if req.Password == "" {
+		return newValidationError("password required")
+	}
+
+	var account models.Account
+	if err := s.db.Preload("Roles").
+		Where("id = ?", id).
+		First(&account).Error; err != nil {
+		return err
+	}
+	if !hasPermissions(ctx, accountPermissions(&account)) {
+		adminForbidden(ctx, "the account has permissions you do not have")
+		return nil
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.Model(&account).
+		Update("password", string(hash)).Error; err != nil {
+		return err
+	}
+
+	ctx.Redirect(http.StatusSeeOther, "/admin/accounts/"+id.String())
+	return nil
+}
+
+type AdminAccountUpdateRolesRequest struct {
+	RoleIDs []string `form:"role_ids"`
+}
+
+func (s *Server) adminAccountUpdateRoles(ctx *gin.Context) error {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return newValidationError("invalid id")
+	}
+
+	var req AdminAccountUpdateRolesRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		return newValidationError(err.Error())
+	}
+
+	var account models.Account
+	if err := s.db.Preload("Roles").
+		Where("id = ?", id).
+		First(&account).Error; err != nil {
+		return err
+	}
+	if !hasPermissions(ctx, accountPermissions(&account)) {
+		adminForbidden(ctx, "the account has permissions you do not have")
+		return nil
+	}
+
+	roles := []*models.Role{}
+	if len(req.RoleIDs) > 0 {
+		if err := s.db.Where("id IN ?", req.RoleIDs).Find(&roles).Error; err != nil {
+			return err
+		}
+	}
+	if !hasPermissions(ctx, accountPermissions(&models.Account{Roles: roles})) {
+		adminForbidden(ctx, "the roles have permissions you do not have")
+		return nil
+	}
+
+	if err := s.db.Model(&account).Association("Roles").Replace(roles); err != nil {
+		return err
+	}
+
+	ctx.Redirect(http.StatusSeeOther, "/admin/accounts/"+id.String())
+	return nil
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/ophum/simpleident/models"
+	"gorm.io/gorm"
+)
+
+const adminPermissionsKey = "admin_permissions"
+
+// adminAuthMiddleware requires a signed-in account with at least one admin permission.
+func (s *Server) adminAuthMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		session := sessions.Default(ctx)
+
+		accountID, ok := session.Get("account_id").(string)
+		if !ok {
+			v := url.Values{}
+			v.Set("return", ctx.Request.URL.String())
+			ctx.Redirect(http.StatusSeeOther, "/sign-in?"+v.Encode())
+			ctx.Abort()
+			return
+		}
+
+		var account models.Account
+		if err := s.db.Preload("Roles").
+			Where("id = ?", accountID).
+			First(&account).Error; err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				_ = ctx.Error(err)
+			}
+			adminForbidden(ctx, "admin role required")
+			return
+		}
+
+		permissions := accountPermissions(&account)
+		if len(permissions) == 0 {
+			adminForbidden(ctx, "admin role required")
+			return
+		}
+
+		ctx.Set(adminPermissionsKey, permissions)
+		ctx.Next()
+	}
+}
+
+// requirePermission must be used after adminAuthMiddleware.
+func requirePermission(permission models.Permission) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !hasPermission(ctx, permission) {
+			adminForbidden(ctx, string(permission)+" permission required")
+			return
+		}
+		ctx.Next()
+	}
+}
+
+func hasPermission(ctx *gin.Context, permission models.Permission) bool {
+	permissions, _ := ctx.Value(adminPermissionsKey).([]models.Permission)
+	return slices.Contains(permissions, permission)
+}
+
+// hasPermissions reports whether the administrator holds every one of permissions.
+// Accounts and roles with permissions beyond the administrator's own must not be managed by them,
+// otherwise e.g. resetting the password of a more privileged account escalates their privileges.
+func hasPermissions(ctx *gin.Context, permissions []models.Permission) bool {
+	for _, p := range permissions {
+		if !hasPermission(ctx, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// accountPermissions returns the known permissions granted by the roles of the account.
+func accountPermissions(account *models.Account) []models.Permission {
+	permissions := []models.Permission{}
+	for _, role := range account.Roles {
+		for _, p := range parsePermissions(role.Permissions) {
+			if !slices.Contains(permissions, p) {
+				permissions = append(permissions, p)
+			}
+		}
+	}
+	return permissions
+}
+
+// parsePermissions parses space-delimited permissions, dropping unknown ones.
+func parsePermissions(s string) []models.Permission {
+	permissions := []models.Permission{}
+	for _, p := range parseScope(s) {
+		if slices.Contains(models.Permissions, models.Permission(p)) {
+			permissions = append(permissions, models.Permission(p))
+		}
+	}
+	return permissions
+}
+
+func formatPermissions(permissions []models.Permission) string {
+	s := []string{}
+	for _, p := range permissions {
+		s = append(s, string(p))
+	}
+	return formatScope(s)
+}
+
+func adminForbidden(ctx *gin.Context, description string) {
+	ctx.HTML(http.StatusForbidden, "error", gin.H{
+		"Status":      http.StatusForbidden,
+		"Code":        "forbidden",
+		"Description": description,
+	})
+	ctx.Abort()
+}
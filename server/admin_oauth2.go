@@ -3,8 +3,10 @@ package server
 import (
 	"crypto/rand"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-jose/go-jose/v4"
 	"github.com/google/uuid"
 	"github.com/ophum/simpleident/models"
 	csrf "github.com/utrack/gin-csrf"
@@ -13,19 +15,22 @@ import (
 func (s *Server) adminOauth2ClientDetail(ctx *gin.Context) error {
 	id, err := uuid.Parse(ctx.Param("id"))
 	if err != nil {
-		return err
+		return newValidationError("invalid id")
 	}
 
 	var client models.Oauth2Client
 	if err := s.db.Preload("ClientSecrets").
+		Preload("RedirectURIs").
 		Where("id = ?", id).
 		First(&client).Error; err != nil {
 		return err
 	}
 
 	ctx.HTML(http.StatusOK, "admin/oauth2-client-detail", gin.H{
-		"Client":    client,
-		"CSRFToken": csrf.GetToken(ctx),
+		"Client":             client,
+		"CanWrite":           hasPermission(ctx, models.PermissionClientsWrite),
+		"CanGenerateSecrets": hasPermission(ctx, models.PermissionSecretsGenerate),
+		"CSRFToken":          csrf.GetToken(ctx),
 	})
 	return nil
 }
@@ -33,6 +38,7 @@ func (s *Server) adminOauth2ClientDetail(ctx *gin.Context) error {
 func (s *Server) adminOauth2ClientList(ctx *gin.Context) error {
 	var clients []*models.Oauth2Client
 	if err := s.db.Preload("ClientSecrets").
+		Preload("RedirectURIs").
 		Find(&clients).Error; err != nil {
 		return err
 	}
@@ -53,12 +59,56 @@ func (s *Server) adminOauth2ClientNew(ctx *gin.Context) error {
 type AdminOauth2ClientCreateRequest struct {
 	Name        string `form:"name"`
 	Description string `form:"description"`
-	CallbackURL string `form:"callback_url"`
+	// one redirect URI per line
+	RedirectURIs  string `form:"redirect_uris"`
+	AllowedScopes string `form:"allowed_scopes"`
+	RequirePKCE   bool   `form:"require_pkce"`
+
+	IDTokenSigningAlg string                         `form:"id_token_signing_alg"`
+	AccessTokenFormat models.Oauth2AccessTokenFormat `form:"access_token_format"`
+	Audiences         string                         `form:"audiences"`
+
+	IsResourceServer bool `form:"is_resource_server"`
+	Trusted          bool `form:"trusted"`
 }
 
 func (s *Server) adminOauth2ClientCreate(ctx *gin.Context) error {
 	var req AdminOauth2ClientCreateRequest
 	if err := ctx.ShouldBind(&req); err != nil {
+		return newValidationError(err.Error())
+	}
+
+	if req.IDTokenSigningAlg != "" {
+		if _, err := s.keys.SigningKey(jose.SignatureAlgorithm(req.IDTokenSigningAlg)); err != nil {
+			return newValidationError("unsupported id_token_signing_alg")
+		}
+	}
+
+	var redirectURIs []*models.Oauth2ClientRedirectURI
+	for _, uri := range strings.Fields(req.RedirectURIs) {
+		if err := validateRedirectURI(uri); err != nil {
+			return newValidationError(err.Error())
+		}
+
+		id, err := uuid.NewV7()
+		if err != nil {
+			return err
+		}
+		redirectURIs = append(redirectURIs, &models.Oauth2ClientRedirectURI{
+			Model: models.Model{
+				ID: id,
+			},
+			URI: uri,
+		})
+	}
+
+	accessTokenFormat, err := validateAccessTokenFormat(req.AccessTokenFormat)
+	if err != nil {
+		return err
+	}
+
+	audiences, err := validateAudiences(req.Audiences)
+	if err != nil {
 		return err
 	}
 
@@ -70,9 +120,18 @@ func (s *Server) adminOauth2ClientCreate(ctx *gin.Context) error {
 		Model: models.Model{
 			ID: id,
 		},
-		Name:        req.Name,
-		Description: req.Description,
-		CallbackURL: req.CallbackURL,
+		Name:          req.Name,
+		Description:   req.Description,
+		RedirectURIs:  redirectURIs,
+		AllowedScopes: formatScope(parseScope(req.AllowedScopes)),
+		RequirePKCE:   req.RequirePKCE,
+
+		IDTokenSigningAlg: req.IDTokenSigningAlg,
+		AccessTokenFormat: accessTokenFormat,
+		Audiences:         audiences,
+
+		IsResourceServer: req.IsResourceServer,
+		Trusted:          req.Trusted,
 	}).Error; err != nil {
 		return err
 	}
@@ -81,12 +140,168 @@ func (s *Server) adminOauth2ClientCreate(ctx *gin.Context) error {
 	return nil
 }
 
-func (s *Server) adminOauth2ClientGenerateSecret(ctx *gin.Context) error {
+// validateAccessTokenFormat defaults to opaque tokens if the format is empty.
+func validateAccessTokenFormat(format models.Oauth2AccessTokenFormat) (models.Oauth2AccessTokenFormat, error) {
+	switch format {
+	case "":
+		return models.Oauth2AccessTokenFormatOpaque, nil
+	case models.Oauth2AccessTokenFormatOpaque, models.Oauth2AccessTokenFormatJWT:
+		return format, nil
+	}
+	return "", newValidationError("invalid access_token_format")
+}
+
+// validateAudiences normalizes space-delimited resource indicators.
+func validateAudiences(audiences string) (string, error) {
+	resources := parseScope(audiences)
+	for _, resource := range resources {
+		if err := validateResourceIndicator(resource); err != nil {
+			return "", newValidationError(err.Error())
+		}
+	}
+	return formatScope(resources), nil
+}
+
+type AdminOauth2ClientUpdateScopesRequest struct {
+	AllowedScopes string `form:"allowed_scopes"`
+}
+
+func (s *Server) adminOauth2ClientUpdateScopes(ctx *gin.Context) error {
+	clientID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return newValidationError("invalid id")
+	}
+
+	var req AdminOauth2ClientUpdateScopesRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		return newValidationError(err.Error())
+	}
+
+	if err := s.db.Model(&models.Oauth2Client{}).
+		Where("id = ?", clientID).
+		Update("allowed_scopes", formatScope(parseScope(req.AllowedScopes))).Error; err != nil {
+		return err
+	}
+
+	ctx.Redirect(http.StatusSeeOther, "/admin/oauth2/clients/"+clientID.String())
+	return nil
+}
+
+// unchecked checkboxes are not sent, so every setting is submitted in the same form.
+type AdminOauth2ClientUpdateSettingsRequest struct {
+	RequirePKCE       bool                           `form:"require_pkce"`
+	IsResourceServer  bool                           `form:"is_resource_server"`
+	AccessTokenFormat models.Oauth2AccessTokenFormat `form:"access_token_format"`
+	Audiences         string                         `form:"audiences"`
+	Trusted           bool                           `form:"trusted"`
+}
+
+func (s *Server) adminOauth2ClientUpdateSettings(ctx *gin.Context) error {
+	clientID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return newValidationError("invalid id")
+	}
+
+	var req AdminOauth2ClientUpdateSettingsRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		return newValidationError(err.Error())
+	}
+
+	accessTokenFormat, err := validateAccessTokenFormat(req.AccessTokenFormat)
+	if err != nil {
+		return err
+	}
+
+	audiences, err := validateAudiences(req.Audiences)
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.Model(&models.Oauth2Client{}).
+		Where("id = ?", clientID).
+		Updates(map[string]any{
+			"require_pkce":        req.RequirePKCE,
+			"is_resource_server":  req.IsResourceServer,
+			"access_token_format": accessTokenFormat,
+			"audiences":           audiences,
+			"trusted":             req.Trusted,
+		}).Error; err != nil {
+		return err
+	}
+
+	ctx.Redirect(http.StatusSeeOther, "/admin/oauth2/clients/"+clientID.String())
+	return nil
+}
+
+type AdminOauth2ClientAddRedirectURIRequest struct {
+	RedirectURI string `form:"redirect_uri"`
+}
+
+func (s *Server) adminOauth2ClientAddRedirectURI(ctx *gin.Context) error {
+	clientID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return newValidationError("invalid id")
+	}
+
+	var req AdminOauth2ClientAddRedirectURIRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		return newValidationError(err.Error())
+	}
+
+	if err := validateRedirectURI(req.RedirectURI); err != nil {
+		return newValidationError(err.Error())
+	}
+
+	var client models.Oauth2Client
+	if err := s.db.Where("id = ?", clientID).First(&client).Error; err != nil {
+		return err
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.Create(&models.Oauth2ClientRedirectURI{
+		Model: models.Model{
+			ID: id,
+		},
+		Oauth2ClientID: clientID,
+		URI:            req.RedirectURI,
+	}).Error; err != nil {
+		return err
+	}
+
+	ctx.Redirect(http.StatusSeeOther, "/admin/oauth2/clients/"+clientID.String())
+	return nil
+}
+
+func (s *Server) adminOauth2ClientDeleteRedirectURI(ctx *gin.Context) error {
 	clientID, err := uuid.Parse(ctx.Param("id"))
 	if err != nil {
+		return newValidationError("invalid id")
+	}
+
+	redirectURIID, err := uuid.Parse(ctx.Param("redirectURIID"))
+	if err != nil {
+		return newValidationError("invalid redirect URI id")
+	}
+
+	if err := s.db.Where("id = ? AND oauth2_client_id = ?", redirectURIID, clientID).
+		Delete(&models.Oauth2ClientRedirectURI{}).Error; err != nil {
 		return err
 	}
 
+	ctx.Redirect(http.StatusSeeOther, "/admin/oauth2/clients/"+clientID.String())
+	return nil
+}
+
+func (s *Server) adminOauth2ClientGenerateSecret(ctx *gin.Context) error {
+	clientID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return newValidationError("invalid id")
+	}
+
 	var client models.Oauth2Client
 	if err := s.db.Where("id = ?", clientID).First(&client).Error; err != nil {
 		return err
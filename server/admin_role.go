@@ -0,0 +1,140 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ophum/simpleident/models"
+	csrf "github.com/utrack/gin-csrf"
+)
+
+func (s *Server) adminRoleList(ctx *gin.Context) error {
+	var roles []*models.Role
+	if err := s.db.Order("name").Find(&roles).Error; err != nil {
+		return err
+	}
+
+	ctx.HTML(http.StatusOK, "admin/role-list", gin.H{
+		"Roles": roles,
+	})
+	return nil
+}
+
+func (s *Server) adminRoleNew(ctx *gin.Context) error {
+	ctx.HTML(http.StatusOK, "admin/role-new", gin.H{
+		"Permissions": models.Permissions,
+		"CSRFToken":   csrf.GetToken(ctx),
+	})
+	return nil
+}
+
+type AdminRoleCreateRequest struct {
+	Name        string   `form:"name"`
+	Description string   `form:"description"`
+	Permissions []string `form:"permissions"`
+}
+
+func (s *Server) adminRoleCreate(ctx *gin.Context) error {
+	var req AdminRoleCreateRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		return newValidationError(err.Error())
+	}
+	if req.Name == "" {
+		return newValidationError("name required")
+	}
+
+	permissions := parsePermissions(formatScope(req.Permissions))
+	if !hasPermissions(ctx, permissions) {
+		adminForbidden(ctx, "you cannot grant permissions you do not have")
+		return nil
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+	if err := s.db.Create(&models.Role{
+		Model: models.Model{
+			ID: id,
+		},
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: formatPermissions(permissions),
+	}).Error; err != nil {
+		return err
+	}
+
+	ctx.Redirect(http.StatusSeeOther, "/admin/roles")
+	return nil
+}
+
+func (s *Server) adminRoleDetail(ctx *gin.Context) error {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return newValidationError("invalid id")
+	}
+
+	var role models.Role
+	if err := s.db.Where("id = ?", id).First(&role).Error; err != nil {
+		return err
+	}
+
+	var accounts []*models.Account
+	if err := s.db.Joins("JOIN account_roles ON account_roles.account_id = accounts.id").
+		Where("account_roles.role_id = ?", role.ID).
+		Find(&accounts).Error; err != nil {
+		return err
+	}
+
+	granted := map[models.Permission]bool{}
+	for _, p := range parsePermissions(role.Permissions) {
+		granted[p] = true
+	}
+
+	ctx.HTML(http.StatusOK, "admin/role-detail", gin.H{
+		"Role":               role,
+		"Accounts":           accounts,
+		"Permissions":        models.Permissions,
+		"GrantedPermissions": granted,
+		"CanWrite":           hasPermissions(ctx, parsePermissions(role.Permissions)),
+		"CSRFToken":          csrf.GetToken(ctx),
+	})
+	return nil
+}
+
+type AdminRoleUpdatePermissionsRequest struct {
+	Permissions []string `form:"permissions"`
+}
+
+func (s *Server) adminRoleUpdatePermissions(ctx *gin.Context) error {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return newValidationError("invalid id")
+	}
+
+	var req AdminRoleUpdatePermissionsRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		return newValidationError(err.Error())
+	}
+
+	var role models.Role
+	if err := s.db.Where("id = ?", id).First(&role).Error; err != nil {
+		return err
+	}
+
+	// both the permissions taken away and the ones granted must be the administrator's own
+	permissions := parsePermissions(formatScope(req.Permissions))
+	if !hasPermissions(ctx, parsePermissions(role.Permissions)) || !hasPermissions(ctx, permissions) {
+		adminForbidden(ctx, "you cannot change permissions you do not have")
+		return nil
+	}
+
+	if err := s.db.Model(&role).
+		Update("permissions", formatPermissions(permissions)).Error; err != nil {
+		return err
+	}
+
+	ctx.Redirect(http.StatusSeeOther, "/admin/roles/"+id.String())
+	return nil
+}
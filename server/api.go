@@ -8,29 +8,59 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/ophum/simpleident/models"
+	"gorm.io/gorm"
 )
 
+// no error code is returned if the request lacks any authentication information (RFC 6750 3.1).
+var errMissingBearerToken = &Oauth2Error{}
+
 func (s *Server) apiGetUserinfo(ctx *gin.Context) error {
 	bearerToken := ctx.GetHeader("Authorization")
 	token, ok := strings.CutPrefix(bearerToken, "Bearer ")
 	if !ok {
-		return errors.New("invalid authorization")
+		return errMissingBearerToken
+	}
+
+	// JWT access tokens are verified before looking up, which also tells whether they have been revoked.
+	if isJWT(token) {
+		if _, err := s.verifyJWTAccessToken(ctx, token); err != nil {
+			return newOauth2Error(Oauth2ErrorInvalidToken, err.Error())
+		}
 	}
 
 	var oauth2Token models.Oauth2Token
 	if err := s.db.Preload("Account").
 		Where("token = ?", token).
 		First(&oauth2Token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return newOauth2Error(Oauth2ErrorInvalidToken, "invalid token")
+		}
 		return err
 	}
 
-	if oauth2Token.CreatedAt.Add(time.Hour).Before(time.Now()) {
-		return errors.New("token expired")
+	if oauth2Token.ExpiresAt.Before(time.Now()) {
+		return newOauth2Error(Oauth2ErrorInvalidToken, "token expired")
+	}
+
+	// tokens issued by the client credentials grant have no account to describe.
+	if oauth2Token.AccountID == nil {
+		return newOauth2Error(Oauth2ErrorInsufficientScope, "token is not issued to an account")
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{
-		"id":       oauth2Token.Account.ID,
-		"username": oauth2Token.Account.Username,
-	})
+	if !hasScope(oauth2Token.Scope, Oauth2ScopeOpenID) && !hasScope(oauth2Token.Scope, Oauth2ScopeProfile) {
+		return newOauth2Error(Oauth2ErrorInsufficientScope, "openid or profile scope required")
+	}
+
+	// sub and preferred_username are the claims of the OpenID Connect UserInfo response (OpenID Connect Core 1.0 5.3.2),
+	// sub is the subject of the ID token. id and username are kept for existing clients.
+	res := gin.H{
+		"sub": oauth2Token.Account.ID.String(),
+		"id":  oauth2Token.Account.ID,
+	}
+	if hasScope(oauth2Token.Scope, Oauth2ScopeProfile) {
+		res["preferred_username"] = oauth2Token.Account.Username
+		res["username"] = oauth2Token.Account.Username
+	}
+	ctx.JSON(http.StatusOK, res)
 	return nil
 }
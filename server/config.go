@@ -0,0 +1,35 @@
+package server
+
+import "time"
+
+type Config struct {
+	// serve the admin UI on the same engine as the public endpoints
+	EnableAdmin bool
+	// issuer identifier and public base URL, derived from each request if empty
+	Issuer     string
+	CSRFSecret string
+
+	AuthorizationCodeLifetime time.Duration
+	AccessTokenLifetime       time.Duration
+	RefreshTokenLifetime      time.Duration
+	IDTokenLifetime           time.Duration
+	DeviceCodeLifetime        time.Duration
+}
+
+// DefaultConfig returns the configuration used for anything left unset.
+func DefaultConfig() *Config {
+	return &Config{
+		EnableAdmin:               true,
+		CSRFSecret:                "secret",
+		AuthorizationCodeLifetime: time.Minute * 5,
+		AccessTokenLifetime:       time.Hour,
+		RefreshTokenLifetime:      time.Hour * 24 * 30,
+		IDTokenLifetime:           time.Hour,
+		DeviceCodeLifetime:        time.Minute * 10,
+	}
+}
+
+// SignedTokenLifetime returns the longest lifetime of tokens signed with the signing keys.
+func (c *Config) SignedTokenLifetime() time.Duration {
+	return max(c.IDTokenLifetime, c.AccessTokenLifetime)
+}
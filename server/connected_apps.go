@@ -0,0 +1,161 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ophum/simpleident/models"
+	csrf "github.com/utrack/gin-csrf"
+	"gorm.io/gorm"
+)
+
+type ConnectedApp struct {
+	Client *models.Oauth2Client
+	Scopes []Oauth2ScopeItem
+	// zero if the client has never been granted consent (trusted clients)
+	GrantedAt time.Time
+	// when the latest access token was issued, zero if none
+	LastIssuedAt time.Time
+}
+
+func (s *Server) connectedApps(ctx *gin.Context) error {
+	session := sessions.Default(ctx)
+
+	accountID, ok := session.Get("account_id").(string)
+	if !ok {
+		ctx.Redirect(http.StatusFound, "/sign-in")
+		return nil
+	}
+
+	var consents []*models.Oauth2Consent
+	if err := s.db.Where("account_id = ?", accountID).
+		Find(&consents).Error; err != nil {
+		return err
+	}
+
+	// clients holding tokens are listed even without consent
+	var tokenClientIDs []uuid.UUID
+	if err := s.db.Model(&models.Oauth2Token{}).
+		Where("account_id = ? AND expires_at > ?", accountID, time.Now()).
+		Distinct().
+		Pluck("oauth2_client_id", &tokenClientIDs).Error; err != nil {
+		return err
+	}
+	var refreshTokenClientIDs []uuid.UUID
+	if err := s.db.Model(&models.Oauth2RefreshToken{}).
+		Where("account_id = ? AND used_at IS NULL AND expires_at > ?", accountID, time.Now()).
+		Distinct().
+		Pluck("oauth2_client_id", &refreshTokenClientIDs).Error; err != nil {
+		return err
+	}
+
+	// approved device codes which have not been exchanged for tokens yet
+	var deviceCodes []*models.Oauth2DeviceCode
+	if err := s.db.Where("account_id = ? AND status = ? AND expires_at > ?",
+		accountID, models.Oauth2DeviceCodeStatusApproved, time.Now()).
+		Find(&deviceCodes).Error; err != nil {
+		return err
+	}
+	var deviceCodeClientIDs []uuid.UUID
+	for _, deviceCode := range deviceCodes {
+		deviceCodeClientIDs = append(deviceCodeClientIDs, deviceCode.Oauth2ClientID)
+	}
+
+	clientIDs := []uuid.UUID{}
+	for _, consent := range consents {
+		clientIDs = append(clientIDs, consent.Oauth2ClientID)
+	}
+	for _, id := range slices.Concat(tokenClientIDs, refreshTokenClientIDs, deviceCodeClientIDs) {
+		if !slices.Contains(clientIDs, id) {
+			clientIDs = append(clientIDs, id)
+		}
+	}
+
+	apps := []*ConnectedApp{}
+	for _, clientID := range clientIDs {
+		var client models.Oauth2Client
+		if err := s.db.Where("id = ?", clientID).First(&client).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return err
+		}
+		app := &ConnectedApp{
+			Client: &client,
+		}
+
+		for _, deviceCode := range deviceCodes {
+			if deviceCode.Oauth2ClientID == clientID {
+				app.Scopes = oauth2ScopeItems(deviceCode.Scope)
+			}
+		}
+
+		var token models.Oauth2Token
+		if err := s.db.Where("account_id = ? AND oauth2_client_id = ?", accountID, clientID).
+			Order("created_at DESC").
+			First(&token).Error; err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		} else {
+			app.LastIssuedAt = token.CreatedAt
+			app.Scopes = oauth2ScopeItems(token.Scope)
+		}
+
+		for _, consent := range consents {
+			if consent.Oauth2ClientID == clientID {
+				app.GrantedAt = consent.GrantedAt
+				app.Scopes = oauth2ScopeItems(consent.Scope)
+			}
+		}
+		apps = append(apps, app)
+	}
+
+	ctx.HTML(http.StatusOK, "connected-apps", gin.H{
+		"Apps":      apps,
+		"CSRFToken": csrf.GetToken(ctx),
+	})
+	return nil
+}
+
+func (s *Server) connectedAppsRevoke(ctx *gin.Context) error {
+	session := sessions.Default(ctx)
+
+	accountID, ok := session.Get("account_id").(string)
+	if !ok {
+		ctx.Redirect(http.StatusFound, "/sign-in")
+		return nil
+	}
+
+	clientID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		return newValidationError("invalid id")
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []any{
+			&models.Oauth2Consent{},
+			&models.Oauth2Code{},
+			&models.Oauth2Token{},
+			&models.Oauth2RefreshToken{},
+			// approved device codes would still be exchanged for tokens
+			&models.Oauth2DeviceCode{},
+		} {
+			if err := tx.Where("account_id = ? AND oauth2_client_id = ?", accountID, clientID).
+				Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	ctx.Redirect(http.StatusSeeOther, "/connected-apps")
+	return nil
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) registerHealthRoutes(r gin.IRouter) {
+	r.GET("/healthz", s.healthz)
+	r.GET("/readyz", s.readyz)
+}
+
+// Drain makes /readyz fail so that no new traffic is routed to the server while it shuts down.
+func (s *Server) Drain() {
+	s.draining.Store(true)
+}
+
+// healthz reports the process is alive.
+func (s *Server) healthz(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+	})
+}
+
+// readyz reports the server can handle requests: the database is reachable and the signing keys are loaded.
+func (s *Server) readyz(ctx *gin.Context) {
+	checks := gin.H{}
+	ready := true
+
+	if s.draining.Load() {
+		checks["server"] = "shutting down"
+		ready = false
+	}
+
+	db, err := s.db.DB()
+	if err == nil {
+		err = db.PingContext(ctx.Request.Context())
+	}
+	if err != nil {
+		_ = ctx.Error(err)
+		checks["database"] = "unavailable"
+		ready = false
+	} else {
+		checks["database"] = "ok"
+	}
+
+	if s.keys.Loaded() {
+		checks["signing_keys"] = "ok"
+	} else {
+		checks["signing_keys"] = "not loaded"
+		ready = false
+	}
+
+	status := http.StatusOK
+	statusText := "ok"
+	if !ready {
+		status = http.StatusServiceUnavailable
+		statusText = "unavailable"
+	}
+	ctx.JSON(status, gin.H{
+		"status": statusText,
+		"checks": checks,
+	})
+}
@@ -4,7 +4,6 @@ import (
 	"errors"
 	"net/http"
 	"net/url"
-	"strings"
 	"time"
 
 	"github.com/gin-contrib/sessions"
@@ -12,6 +11,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/ophum/simpleident/models"
 	csrf "github.com/utrack/gin-csrf"
+	"gorm.io/gorm"
 )
 
 type Oauth2ResponseType string
@@ -25,6 +25,12 @@ type Oauth2AuthorizeRequest struct {
 	ClientID     string             `form:"client_id"`
 	RedirectURI  string             `form:"redirect_uri"`
 	State        string             `form:"state"`
+	Scope        string             `form:"scope"`
+	Nonce        string             `form:"nonce"`
+	Prompt       string             `form:"prompt"`
+
+	CodeChallenge       string                    `form:"code_challenge"`
+	CodeChallengeMethod Oauth2CodeChallengeMethod `form:"code_challenge_method"`
 }
 
 func (s *Server) oauth2Authorize(ctx *gin.Context) error {
@@ -32,43 +38,60 @@ func (s *Server) oauth2Authorize(ctx *gin.Context) error {
 
 	var req Oauth2AuthorizeRequest
 	if err := ctx.ShouldBind(&req); err != nil {
-		return err
-	}
-
-	if req.ResponseType != Oauth2ResponseTypeCode {
-		return errors.New("invalid response type")
+		return newOauth2Error(Oauth2ErrorInvalidRequest, err.Error())
 	}
 
+	// errors are not redirected until the client and the redirect_uri are validated (RFC 6749 4.1.2.1).
 	clientID, err := uuid.Parse(req.ClientID)
 	if err != nil {
-		return err
+		return newOauth2Error(Oauth2ErrorInvalidRequest, "invalid client_id")
 	}
 
 	var client models.Oauth2Client
-	if err := s.db.Where("id = ?", clientID).First(&client).Error; err != nil {
+	if err := s.db.Preload("RedirectURIs").
+		Preload("ClientSecrets").
+		Where("id = ?", clientID).
+		First(&client).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return newOauth2Error(Oauth2ErrorInvalidRequest, "unknown client_id")
+		}
 		return err
 	}
 
-	redirectURI := client.CallbackURL
-	if req.RedirectURI != "" {
-		u, err := url.Parse(req.RedirectURI)
-		if err != nil {
-			return err
-		}
+	redirectURI, err := matchRedirectURI(client.RedirectURIs, req.RedirectURI)
+	if err != nil {
+		return newOauth2Error(Oauth2ErrorInvalidRequest, err.Error())
+	}
 
-		if u.Fragment != "" {
-			return errors.New("invalid redirect_uri")
-		}
+	if req.ResponseType == "" {
+		return newOauth2Error(Oauth2ErrorInvalidRequest, "response_type required").
+			WithRedirect(redirectURI, req.State)
+	}
+	if req.ResponseType != Oauth2ResponseTypeCode {
+		return newOauth2Error(Oauth2ErrorUnsupportedResponseType, "").
+			WithRedirect(redirectURI, req.State)
+	}
 
-		if !strings.HasPrefix(u.String(), client.CallbackURL) {
-			return errors.New("invalid redirect_uri")
-		}
+	scope, err := resolveScope(req.Scope, client.AllowedScopes)
+	if err != nil {
+		return newOauth2Error(Oauth2ErrorInvalidScope, "").
+			WithRedirect(redirectURI, req.State)
+	}
 
-		redirectURI = req.RedirectURI
+	codeChallengeMethod, err := validatePKCEChallenge(req.CodeChallenge, req.CodeChallengeMethod, pkceRequired(&client))
+	if err != nil {
+		return newOauth2Error(Oauth2ErrorInvalidRequest, err.Error()).
+			WithRedirect(redirectURI, req.State)
 	}
+
 	session.Set("redirect_uri", redirectURI)
+	session.Set("requested_redirect_uri", req.RedirectURI)
 	session.Set("client_id", client.ID.String())
 	session.Set("state", req.State)
+	session.Set("scope", scope)
+	session.Set("nonce", req.Nonce)
+	session.Set("code_challenge", req.CodeChallenge)
+	session.Set("code_challenge_method", string(codeChallengeMethod))
 	if err := session.Save(); err != nil {
 		return err
 	}
@@ -86,21 +109,39 @@ func (s *Server) oauth2Authorize(ctx *gin.Context) error {
 		return err
 	}
 
+	consentRequired, err := s.oauth2ConsentRequired(&client, account.ID, scope, req.Prompt)
+	if err != nil {
+		return err
+	}
+	if !consentRequired {
+		return s.oauth2AuthorizeRedirect(ctx, &client, account.ID)
+	}
+
 	ctx.HTML(http.StatusOK, "oauth2-authorize", gin.H{
 		"CSRFToken":   csrf.GetToken(ctx),
 		"Client":      client,
 		"Account":     account,
 		"RedirectURI": redirectURI,
+		"Scopes":      oauth2ScopeItems(scope),
 	})
 	return nil
 }
 
+type Oauth2PostAuthorizeRequest struct {
+	Action string `form:"action"`
+}
+
 func (s *Server) oauth2PostAuthorize(ctx *gin.Context) error {
 	session := sessions.Default(ctx)
 
+	var req Oauth2PostAuthorizeRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		return newOauth2Error(Oauth2ErrorInvalidRequest, err.Error())
+	}
+
 	clientID, ok := session.Get("client_id").(string)
 	if !ok {
-		return errors.New("invalid client_id")
+		return newOauth2Error(Oauth2ErrorInvalidRequest, "no authorization request in progress")
 	}
 
 	var client models.Oauth2Client
@@ -108,14 +149,50 @@ func (s *Server) oauth2PostAuthorize(ctx *gin.Context) error {
 		return err
 	}
 
-	state := session.Get("state").(string)
+	redirectURI, _ := session.Get("redirect_uri").(string)
+	state, _ := session.Get("state").(string)
+	scope, _ := session.Get("scope").(string)
 
-	redirectURI, err := url.Parse(session.Get("redirect_uri").(string))
+	accountIDStr, ok := session.Get("account_id").(string)
+	if !ok {
+		return newOauth2Error(Oauth2ErrorAccessDenied, "not signed in").
+			WithRedirect(redirectURI, state)
+	}
+	accountID, err := uuid.Parse(accountIDStr)
 	if err != nil {
 		return err
 	}
 
-	accountID, err := uuid.Parse(session.Get("account_id").(string))
+	if req.Action == "deny" {
+		return newOauth2Error(Oauth2ErrorAccessDenied, "").
+			WithRedirect(redirectURI, state)
+	}
+
+	if err := grantOauth2Consent(s.db, client.ID, accountID, scope); err != nil {
+		return err
+	}
+
+	return s.oauth2AuthorizeRedirect(ctx, &client, accountID)
+}
+
+// oauth2AuthorizeRedirect issues an authorization code for the request in progress
+// and redirects back to the client.
+func (s *Server) oauth2AuthorizeRedirect(ctx *gin.Context, client *models.Oauth2Client, accountID uuid.UUID) error {
+	session := sessions.Default(ctx)
+
+	redirectURIStr, _ := session.Get("redirect_uri").(string)
+	requestedRedirectURI, _ := session.Get("requested_redirect_uri").(string)
+	state, _ := session.Get("state").(string)
+	scope, _ := session.Get("scope").(string)
+	nonce, _ := session.Get("nonce").(string)
+	var authTime time.Time
+	if t, ok := session.Get("auth_time").(int64); ok {
+		authTime = time.Unix(t, 0)
+	}
+	codeChallenge, _ := session.Get("code_challenge").(string)
+	codeChallengeMethod, _ := session.Get("code_challenge_method").(string)
+
+	redirectURI, err := url.Parse(redirectURIStr)
 	if err != nil {
 		return err
 	}
@@ -130,6 +207,11 @@ func (s *Server) oauth2PostAuthorize(ctx *gin.Context) error {
 		return err
 	}
 
+	familyID, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+
 	if err := s.db.Create(&models.Oauth2Code{
 		Model: models.Model{
 			ID: codeID,
@@ -137,13 +219,23 @@ func (s *Server) oauth2PostAuthorize(ctx *gin.Context) error {
 		Oauth2ClientID: client.ID,
 		Code:           code,
 		AccountID:      accountID,
+		Scope:          scope,
+		Nonce:          nonce,
+		AuthTime:       authTime,
+		RedirectURI:    requestedRedirectURI,
+		FamilyID:       familyID,
+
+		CodeChallenge:       codeChallenge,
+		CodeChallengeMethod: codeChallengeMethod,
 	}).Error; err != nil {
 		return err
 	}
 
 	q := redirectURI.Query()
 	q.Set("code", code)
-	q.Set("state", state)
+	if state != "" {
+		q.Set("state", state)
+	}
 	redirectURI.RawQuery = q.Encode()
 
 	ctx.Redirect(http.StatusFound, redirectURI.String())
@@ -154,6 +246,8 @@ type Oauth2GrantType string
 
 const (
 	Oauth2GrantTypeAuthorizationCode Oauth2GrantType = "authorization_code"
+	Oauth2GrantTypeRefreshToken      Oauth2GrantType = "refresh_token"
+	Oauth2GrantTypeClientCredentials Oauth2GrantType = "client_credentials"
 )
 
 type Oauth2TokenRequest struct {
@@ -161,62 +255,135 @@ type Oauth2TokenRequest struct {
 	Code        string          `form:"code"`
 	RedirectURI string          `form:"redirect_uri"`
 	ClientID    string          `form:"client_id"`
+	Scope       string          `form:"scope"`
+
+	CodeVerifier string `form:"code_verifier"`
+
+	RefreshToken string `form:"refresh_token"`
+
+	DeviceCode string `form:"device_code"`
+
+	// RFC 8707 2
+	Resource string `form:"resource"`
 }
 
-// FIXME: error handling
 func (s *Server) oauth2PostToken(ctx *gin.Context) error {
 	var req Oauth2TokenRequest
 	if err := ctx.ShouldBind(&req); err != nil {
+		return newOauth2Error(Oauth2ErrorInvalidRequest, err.Error())
+	}
+
+	client, err := s.oauth2AuthenticateClient(ctx)
+	if err != nil {
 		return err
 	}
 
-	clientID, err := uuid.Parse(req.ClientID)
+	if req.GrantType == "" {
+		return newOauth2Error(Oauth2ErrorInvalidRequest, "grant_type required")
+	}
+
+	req.Resource, err = oauth2RequestedResource(ctx, client)
 	if err != nil {
 		return err
 	}
 
-	if req.GrantType != Oauth2GrantTypeAuthorizationCode {
-		return errors.New("invalid grant_type")
+	grant, ok := s.oauth2TokenGrants()[req.GrantType]
+	if !ok {
+		return newOauth2Error(Oauth2ErrorUnsupportedGrantType, "")
+	}
+	return grant(ctx, client, &req)
+}
+
+type oauth2TokenGrantFunc func(ctx *gin.Context, client *models.Oauth2Client, req *Oauth2TokenRequest) error
+
+// oauth2TokenGrants returns the grant types the token endpoint accepts.
+func (s *Server) oauth2TokenGrants() map[Oauth2GrantType]oauth2TokenGrantFunc {
+	return map[Oauth2GrantType]oauth2TokenGrantFunc{
+		Oauth2GrantTypeAuthorizationCode: s.oauth2TokenAuthorizationCode,
+		Oauth2GrantTypeRefreshToken:      s.oauth2TokenRefreshToken,
+		Oauth2GrantTypeClientCredentials: s.oauth2TokenClientCredentials,
+		Oauth2GrantTypeDeviceCode:        s.oauth2TokenDeviceCode,
+	}
+}
+
+func (s *Server) oauth2TokenAuthorizationCode(ctx *gin.Context, client *models.Oauth2Client, req *Oauth2TokenRequest) error {
+	if req.Code == "" {
+		return newOauth2Error(Oauth2ErrorInvalidRequest, "code required")
 	}
 
 	var code models.Oauth2Code
 	if err := s.db.Where("code = ?", req.Code).First(&code).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return newOauth2Error(Oauth2ErrorInvalidGrant, "invalid code")
+		}
 		return err
 	}
 
-	if code.Oauth2ClientID != clientID {
-		return errors.New("invalid clientID")
+	if code.Oauth2ClientID != client.ID {
+		return newOauth2Error(Oauth2ErrorInvalidGrant, "code was issued to another client")
 	}
 
-	if code.CreatedAt.Add(time.Minute * 5).Before(time.Now()) {
-		return errors.New("code expired")
+	if err := verifyCodeRedirectURI(code.RedirectURI, req.RedirectURI); err != nil {
+		return newOauth2Error(Oauth2ErrorInvalidGrant, "redirect_uri does not match")
 	}
 
-	token, err := generateSecret(20)
-	if err != nil {
-		return err
+	// the code may have been issued before the client was required to use PKCE
+	if pkceRequired(client) && code.CodeChallenge == "" {
+		return newOauth2Error(Oauth2ErrorInvalidGrant, "code_challenge required")
 	}
-
-	tokenID, err := uuid.NewV7()
-	if err != nil {
-		return err
+	if err := verifyPKCE(code.CodeChallenge, Oauth2CodeChallengeMethod(code.CodeChallengeMethod), req.CodeVerifier); err != nil {
+		return newOauth2Error(Oauth2ErrorInvalidGrant, err.Error())
 	}
 
-	if err := s.db.Create(&models.Oauth2Token{
-		Model: models.Model{
-			ID: tokenID,
-		},
-		Oauth2ClientID: clientID,
-		Token:          token,
-		AccountID:      code.AccountID,
-	}).Error; err != nil {
+	now := time.Now()
+	var res *Oauth2TokenResponse
+	reused := false
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		// marking the code as used only succeeds once, even for concurrent requests.
+		result := tx.Model(&models.Oauth2Code{}).
+			Where("id = ? AND used_at IS NULL", code.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		// the code is redeemed again, so the tokens issued from it are revoked (RFC 6749 4.1.2).
+		if result.RowsAffected == 0 {
+			reused = true
+			return revokeOauth2TokenFamily(tx, code.FamilyID)
+		}
+
+		if code.CreatedAt.Add(s.config.AuthorizationCodeLifetime).Before(now) {
+			return newOauth2Error(Oauth2ErrorInvalidGrant, "code expired")
+		}
+
+		var err error
+		res, err = s.issueOauth2Tokens(ctx, tx, &oauth2TokenGrant{
+			Client:           client,
+			AccountID:        &code.AccountID,
+			FamilyID:         code.FamilyID,
+			Scope:            code.Scope,
+			Resource:         req.Resource,
+			WithRefreshToken: true,
+		})
+		if err != nil {
+			return err
+		}
+
+		if hasScope(code.Scope, Oauth2ScopeOpenID) {
+			res.IDToken, err = s.issueIDToken(ctx, client, code.AccountID, code.AuthTime, code.Nonce)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
 		return err
 	}
-	ctx.JSON(http.StatusOK, gin.H{
-		"access_token":  token,
-		"token_type":    "bearer",
-		"expires_in":    3600,
-		"refresh_token": "",
-	})
+	if reused {
+		return newOauth2Error(Oauth2ErrorInvalidGrant, "code already used")
+	}
+
+	ctx.JSON(http.StatusOK, res)
 	return nil
 }
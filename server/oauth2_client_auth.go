@@ -0,0 +1,108 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ophum/simpleident/models"
+	"gorm.io/gorm"
+)
+
+var errInvalidClient = newOauth2Error(Oauth2ErrorInvalidClient, "client authentication failed")
+
+// oauth2AuthenticateClient authenticates the client of a token endpoint request.
+// It supports client_secret_basic and client_secret_post (RFC 6749 2.3.1).
+// Clients without any registered secret are public clients and are identified by client_id only,
+// their authorization codes are protected by PKCE instead (see pkceRequired).
+func (s *Server) oauth2AuthenticateClient(ctx *gin.Context) (*models.Oauth2Client, error) {
+	clientID, clientSecret, usedBasic, err := oauth2ClientCredentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := uuid.Parse(clientID)
+	if err != nil {
+		return nil, errInvalidClient
+	}
+
+	var client models.Oauth2Client
+	if err := s.db.Preload("ClientSecrets").
+		Where("id = ?", id).
+		First(&client).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errInvalidClient
+		}
+		return nil, err
+	}
+
+	if len(client.ClientSecrets) == 0 {
+		if usedBasic || clientSecret != "" {
+			return nil, errInvalidClient
+		}
+		return &client, nil
+	}
+
+	if clientSecret == "" {
+		return nil, errInvalidClient
+	}
+	for _, secret := range client.ClientSecrets {
+		if subtle.ConstantTimeCompare([]byte(secret.Secret), []byte(clientSecret)) == 1 {
+			return &client, nil
+		}
+	}
+	return nil, errInvalidClient
+}
+
+// oauth2ClientCredentials extracts client_id and client_secret from the Authorization header or the form body.
+func oauth2ClientCredentials(ctx *gin.Context) (clientID, clientSecret string, usedBasic bool, err error) {
+	formClientID := ctx.PostForm("client_id")
+	formClientSecret := ctx.PostForm("client_secret")
+
+	authorization := ctx.GetHeader("Authorization")
+	if authorization == "" {
+		if formClientID == "" {
+			return "", "", false, errInvalidClient
+		}
+		return formClientID, formClientSecret, false, nil
+	}
+
+	// a client MUST NOT use more than one authentication method in each request.
+	if formClientSecret != "" {
+		return "", "", true, errInvalidClient
+	}
+
+	// the authentication scheme is case-insensitive (RFC 7235 2.1).
+	scheme, encoded, _ := strings.Cut(authorization, " ")
+	if !strings.EqualFold(scheme, "Basic") {
+		return "", "", true, errInvalidClient
+	}
+	encoded = strings.TrimLeft(encoded, " ")
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", "", true, errInvalidClient
+	}
+	rawID, rawSecret, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return "", "", true, errInvalidClient
+	}
+
+	// client_id and client_secret are application/x-www-form-urlencoded before being base64 encoded.
+	clientID, err = url.QueryUnescape(rawID)
+	if err != nil {
+		return "", "", true, errInvalidClient
+	}
+	clientSecret, err = url.QueryUnescape(rawSecret)
+	if err != nil {
+		return "", "", true, errInvalidClient
+	}
+
+	if formClientID != "" && formClientID != clientID {
+		return "", "", true, errInvalidClient
+	}
+	return clientID, clientSecret, true, nil
+}
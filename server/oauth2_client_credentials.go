@@ -0,0 +1,39 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ophum/simpleident/models"
+)
+
+func (s *Server) oauth2TokenClientCredentials(ctx *gin.Context, client *models.Oauth2Client, req *Oauth2TokenRequest) error {
+	// the client credentials grant type MUST only be used by confidential clients (RFC 6749 4.4).
+	if len(client.ClientSecrets) == 0 {
+		return newOauth2Error(Oauth2ErrorUnauthorizedClient, "client_credentials grant is only allowed for confidential clients")
+	}
+
+	scope, err := resolveScope(req.Scope, client.AllowedScopes)
+	if err != nil {
+		return newOauth2Error(Oauth2ErrorInvalidScope, "")
+	}
+
+	familyID, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+
+	res, err := s.issueOauth2Tokens(ctx, s.db, &oauth2TokenGrant{
+		Client:   client,
+		FamilyID: familyID,
+		Scope:    scope,
+		Resource: req.Resource,
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx.JSON(http.StatusOK, res)
+	return nil
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ophum/simpleident/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// OpenID Connect Core 1.0 3.1.2.1
+	oidcPromptConsent = "consent"
+)
+
+// oauth2ConsentRequired reports whether the account has to be asked before the scope is granted to the client.
+func (s *Server) oauth2ConsentRequired(client *models.Oauth2Client, accountID uuid.UUID, scope, prompt string) (bool, error) {
+	if slices.Contains(parseScope(prompt), oidcPromptConsent) {
+		return true, nil
+	}
+
+	if client.Trusted {
+		return false, nil
+	}
+
+	var consent models.Oauth2Consent
+	if err := s.db.Where("account_id = ? AND oauth2_client_id = ?", accountID, client.ID).
+		First(&consent).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return true, nil
+		}
+		return false, err
+	}
+	return !containsScope(consent.Scope, scope), nil
+}
+
+// grantOauth2Consent records that the account has granted the scope to the client,
+// in addition to any scope granted before.
+func grantOauth2Consent(tx *gorm.DB, clientID, accountID uuid.UUID, scope string) error {
+	now := time.Now()
+
+	var consent models.Oauth2Consent
+	err := tx.Where("account_id = ? AND oauth2_client_id = ?", accountID, clientID).
+		First(&consent).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		id, err := uuid.NewV7()
+		if err != nil {
+			return err
+		}
+		return tx.Create(&models.Oauth2Consent{
+			Model: models.Model{
+				ID: id,
+			},
+			AccountID:      accountID,
+			Oauth2ClientID: clientID,
+			Scope:          formatScope(parseScope(scope)),
+			GrantedAt:      now,
+		}).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Model(&consent).Updates(map[string]any{
+		"scope":      formatScope(parseScope(consent.Scope + " " + scope)),
+		"granted_at": now,
+	}).Error
+}
@@ -0,0 +1,325 @@
+package server
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ophum/simpleident/models"
+	csrf "github.com/utrack/gin-csrf"
+	"gorm.io/gorm"
+)
+
+const (
+	Oauth2GrantTypeDeviceCode Oauth2GrantType = "urn:ietf:params:oauth:grant-type:device_code"
+	// minimum polling interval in seconds
+	oauth2DeviceCodeInterval = 5
+)
+
+func (s *Server) oauth2PostDeviceAuthorization(ctx *gin.Context) error {
+	client, err := s.oauth2AuthenticateClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	scope, err := resolveScope(ctx.PostForm("scope"), client.AllowedScopes)
+	if err != nil {
+		return newOauth2Error(Oauth2ErrorInvalidScope, "")
+	}
+
+	deviceCode, err := generateSecret(40)
+	if err != nil {
+		return err
+	}
+
+	userCode, err := generateUserCode()
+	if err != nil {
+		return err
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.Create(&models.Oauth2DeviceCode{
+		Model: models.Model{
+			ID: id,
+		},
+		Oauth2ClientID:  client.ID,
+		DeviceCode:      deviceCode,
+		UserCode:        userCode,
+		Status:          models.Oauth2DeviceCodeStatusPending,
+		Scope:           scope,
+		PollingInterval: oauth2DeviceCodeInterval,
+		ExpiresAt:       time.Now().Add(s.config.DeviceCodeLifetime),
+	}).Error; err != nil {
+		return err
+	}
+
+	verificationURI := s.issuer(ctx) + "/device"
+	v := url.Values{}
+	v.Set("user_code", formatUserCode(userCode))
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"device_code":               deviceCode,
+		"user_code":                 formatUserCode(userCode),
+		"verification_uri":          verificationURI,
+		"verification_uri_complete": verificationURI + "?" + v.Encode(),
+		"expires_in":                int(s.config.DeviceCodeLifetime.Seconds()),
+		"interval":                  oauth2DeviceCodeInterval,
+	})
+	return nil
+}
+
+func (s *Server) oauth2TokenDeviceCode(ctx *gin.Context, client *models.Oauth2Client, req *Oauth2TokenRequest) error {
+	if req.DeviceCode == "" {
+		return newOauth2Error(Oauth2ErrorInvalidRequest, "device_code required")
+	}
+
+	var deviceCode models.Oauth2DeviceCode
+	if err := s.db.Where("device_code = ?", req.DeviceCode).
+		First(&deviceCode).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return newOauth2Error(Oauth2ErrorInvalidGrant, "")
+		}
+		return err
+	}
+
+	if deviceCode.Oauth2ClientID != client.ID {
+		return newOauth2Error(Oauth2ErrorInvalidGrant, "")
+	}
+
+	now := time.Now()
+	if deviceCode.ExpiresAt.Before(now) {
+		return newOauth2Error(Oauth2ErrorExpiredToken, "")
+	}
+
+	switch deviceCode.Status {
+	case models.Oauth2DeviceCodeStatusDenied:
+		return newOauth2Error(Oauth2ErrorAccessDenied, "")
+	case models.Oauth2DeviceCodeStatusPending:
+		polledAt := deviceCode.LastPolledAt
+		deviceCode.LastPolledAt = &now
+
+		// the client polls faster than the interval, so it is asked to slow down by 5 seconds (RFC 8628 3.5).
+		errorCode := Oauth2ErrorAuthorizationPending
+		if polledAt != nil && now.Sub(*polledAt) < time.Duration(deviceCode.PollingInterval)*time.Second {
+			deviceCode.PollingInterval += 5
+			errorCode = Oauth2ErrorSlowDown
+		}
+		if err := s.db.Select("LastPolledAt", "PollingInterval").Save(&deviceCode).Error; err != nil {
+			return err
+		}
+		return newOauth2Error(errorCode, "")
+	}
+
+	var res *Oauth2TokenResponse
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		// the device code is consumed, so tokens are issued only once.
+		result := tx.Where("id = ?", deviceCode.ID).Delete(&models.Oauth2DeviceCode{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		familyID, err := uuid.NewV7()
+		if err != nil {
+			return err
+		}
+
+		res, err = s.issueOauth2Tokens(ctx, tx, &oauth2TokenGrant{
+			Client:           client,
+			AccountID:        deviceCode.AccountID,
+			FamilyID:         familyID,
+			Scope:            deviceCode.Scope,
+			Resource:         req.Resource,
+			WithRefreshToken: true,
+		})
+		return err
+	}); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return newOauth2Error(Oauth2ErrorInvalidGrant, "")
+		}
+		return err
+	}
+
+	ctx.JSON(http.StatusOK, res)
+	return nil
+}
+
+func (s *Server) device(ctx *gin.Context) error {
+	session := sessions.Default(ctx)
+
+	accountID, ok := session.Get("account_id").(string)
+	if !ok {
+		v := url.Values{}
+		v.Set("return", ctx.Request.URL.String())
+		ctx.Redirect(http.StatusSeeOther, "/sign-in?"+v.Encode())
+		return nil
+	}
+
+	var account models.Account
+	if err := s.db.Where("id = ?", accountID).First(&account).Error; err != nil {
+		return err
+	}
+
+	userCode := ctx.Query("user_code")
+	if userCode == "" {
+		ctx.HTML(http.StatusOK, "device", gin.H{
+			"Account": account,
+		})
+		return nil
+	}
+
+	deviceCode, client, err := s.findPendingDeviceCode(userCode)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return s.renderDeviceCodeNotPending(ctx, account.ID)
+		}
+		return err
+	}
+
+	ctx.HTML(http.StatusOK, "device-confirm", gin.H{
+		"CSRFToken": csrf.GetToken(ctx),
+		"Account":   account,
+		"Client":    client,
+		"UserCode":  formatUserCode(deviceCode.UserCode),
+		"Scopes":    oauth2ScopeItems(deviceCode.Scope),
+	})
+	return nil
+}
+
+type DeviceRequest struct {
+	UserCode string `form:"user_code"`
+	Action   string `form:"action"`
+}
+
+func (s *Server) devicePost(ctx *gin.Context) error {
+	session := sessions.Default(ctx)
+
+	accountIDStr, ok := session.Get("account_id").(string)
+	if !ok {
+		ctx.Redirect(http.StatusSeeOther, "/sign-in")
+		return nil
+	}
+	accountID, err := uuid.Parse(accountIDStr)
+	if err != nil {
+		return err
+	}
+
+	var req DeviceRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		return newValidationError(err.Error())
+	}
+
+	deviceCode, client, err := s.findPendingDeviceCode(req.UserCode)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return s.renderDeviceCodeNotPending(ctx, accountID)
+		}
+		return err
+	}
+
+	status := models.Oauth2DeviceCodeStatusDenied
+	if req.Action == "approve" {
+		status = models.Oauth2DeviceCodeStatusApproved
+	}
+
+	// the code may have been approved or denied in another tab, or have expired, since it was looked up.
+	result := s.db.Model(&models.Oauth2DeviceCode{}).
+		Where("id = ? AND status = ? AND expires_at > ?", deviceCode.ID, models.Oauth2DeviceCodeStatusPending, time.Now()).
+		Updates(map[string]any{
+			"status":     status,
+			"account_id": accountID,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return s.renderDeviceCodeNotPending(ctx, accountID)
+	}
+
+	ctx.HTML(http.StatusOK, "device-complete", gin.H{
+		"Client":   client,
+		"Approved": status == models.Oauth2DeviceCodeStatusApproved,
+	})
+	return nil
+}
+
+// renderDeviceCodeNotPending asks for the user code again when it is unknown, expired or already used.
+func (s *Server) renderDeviceCodeNotPending(ctx *gin.Context, accountID uuid.UUID) error {
+	var account models.Account
+	if err := s.db.Where("id = ?", accountID).First(&account).Error; err != nil {
+		return err
+	}
+
+	ctx.HTML(http.StatusOK, "device", gin.H{
+		"Account": account,
+		"Error":   "コードが正しくないか、有効期限が切れています。",
+	})
+	return nil
+}
+
+func (s *Server) findPendingDeviceCode(userCode string) (*models.Oauth2DeviceCode, *models.Oauth2Client, error) {
+	var deviceCode models.Oauth2DeviceCode
+	if err := s.db.Where("user_code = ? AND status = ? AND expires_at > ?",
+		normalizeUserCode(userCode), models.Oauth2DeviceCodeStatusPending, time.Now()).
+		First(&deviceCode).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var client models.Oauth2Client
+	if err := s.db.Where("id = ?", deviceCode.Oauth2ClientID).First(&client).Error; err != nil {
+		return nil, nil, err
+	}
+	return &deviceCode, &client, nil
+}
+
+// user codes use consonants only to avoid confusable characters and accidental words (RFC 8628 6.1).
+const userCodeLetters = "BCDFGHJKLMNPQRSTVWXZ"
+
+func generateUserCode() (string, error) {
+	// rand.Int picks every letter with the same probability, unlike the modulo of a random byte.
+	max := big.NewInt(int64(len(userCodeLetters)))
+
+	code := ""
+	for range 8 {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code += string(userCodeLetters[n.Int64()])
+	}
+	return code, nil
+}
+
+func formatUserCode(code string) string {
+	if len(code) != 8 {
+		return code
+	}
+	return code[:4] + "-" + code[4:]
+}
+
+func normalizeUserCode(code string) string {
+	code = strings.ToUpper(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+func requestBaseURL(ctx *gin.Context) string {
+	scheme := "http"
+	if ctx.Request.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + ctx.Request.Host
+}
@@ -0,0 +1,216 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type Oauth2ErrorCode string
+
+const (
+	// RFC 6749 4.1.2.1, 5.2
+	Oauth2ErrorInvalidRequest          Oauth2ErrorCode = "invalid_request"
+	Oauth2ErrorInvalidClient           Oauth2ErrorCode = "invalid_client"
+	Oauth2ErrorInvalidGrant            Oauth2ErrorCode = "invalid_grant"
+	Oauth2ErrorUnauthorizedClient      Oauth2ErrorCode = "unauthorized_client"
+	Oauth2ErrorUnsupportedGrantType    Oauth2ErrorCode = "unsupported_grant_type"
+	Oauth2ErrorUnsupportedResponseType Oauth2ErrorCode = "unsupported_response_type"
+	Oauth2ErrorInvalidScope            Oauth2ErrorCode = "invalid_scope"
+	Oauth2ErrorAccessDenied            Oauth2ErrorCode = "access_denied"
+	Oauth2ErrorServerError             Oauth2ErrorCode = "server_error"
+	Oauth2ErrorTemporarilyUnavailable  Oauth2ErrorCode = "temporarily_unavailable"
+	// RFC 8628 3.5
+	Oauth2ErrorAuthorizationPending Oauth2ErrorCode = "authorization_pending"
+	Oauth2ErrorSlowDown             Oauth2ErrorCode = "slow_down"
+	Oauth2ErrorExpiredToken         Oauth2ErrorCode = "expired_token"
+	// RFC 8707 2
+	Oauth2ErrorInvalidTarget Oauth2ErrorCode = "invalid_target"
+	// RFC 6750 3.1
+	Oauth2ErrorInvalidToken      Oauth2ErrorCode = "invalid_token"
+	Oauth2ErrorInsufficientScope Oauth2ErrorCode = "insufficient_scope"
+)
+
+// Oauth2Error is an error reported to the client as defined by the OAuth 2.0 specifications.
+type Oauth2Error struct {
+	Code        Oauth2ErrorCode
+	Description string
+
+	// set if the error of the authorization endpoint can be sent back to the client
+	RedirectURI string
+	State       string
+}
+
+func newOauth2Error(code Oauth2ErrorCode, description string) *Oauth2Error {
+	return &Oauth2Error{
+		Code:        code,
+		Description: description,
+	}
+}
+
+func (e *Oauth2Error) Error() string {
+	if e.Description == "" {
+		return string(e.Code)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Description)
+}
+
+// WithRedirect returns a copy of the error which is sent back to redirectURI (RFC 6749 4.1.2.1).
+// It must only be used once redirectURI has been validated.
+func (e *Oauth2Error) WithRedirect(redirectURI, state string) *Oauth2Error {
+	err := *e
+	err.RedirectURI = redirectURI
+	err.State = state
+	return &err
+}
+
+func (e *Oauth2Error) Status() int {
+	switch e.Code {
+	case "", Oauth2ErrorInvalidClient, Oauth2ErrorInvalidToken:
+		return http.StatusUnauthorized
+	case Oauth2ErrorInsufficientScope:
+		return http.StatusForbidden
+	case Oauth2ErrorServerError:
+		return http.StatusInternalServerError
+	case Oauth2ErrorTemporarilyUnavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusBadRequest
+}
+
+func (e *Oauth2Error) response() gin.H {
+	res := gin.H{
+		"error": e.Code,
+	}
+	if e.Description != "" {
+		res["error_description"] = e.Description
+	}
+	return res
+}
+
+// asOauth2Error converts err into an Oauth2Error, errors which are not are reported as server_error.
+func asOauth2Error(ctx *gin.Context, err error) *Oauth2Error {
+	var oauth2Err *Oauth2Error
+	if errors.As(err, &oauth2Err) {
+		return oauth2Err
+	}
+
+	// keep the cause in the log without disclosing it to the client.
+	_ = ctx.Error(err)
+	return newOauth2Error(Oauth2ErrorServerError, "")
+}
+
+// handler renders errors as an HTML page, invalid input as 400, or redirects errors of the authorization endpoint back to the client.
+func handler(fn func(ctx *gin.Context) error) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		err := fn(ctx)
+		if err == nil {
+			return
+		}
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			_ = ctx.Error(err)
+			ctx.HTML(http.StatusNotFound, "error", gin.H{
+				"Status":      http.StatusNotFound,
+				"Code":        "not_found",
+				"Description": "not found",
+			})
+			ctx.Abort()
+			return
+		}
+
+		var validationErr *ValidationError
+		if errors.As(err, &validationErr) {
+			renderValidationError(ctx, validationErr)
+			return
+		}
+
+		oauth2Err := asOauth2Error(ctx, err)
+		if oauth2Err.RedirectURI != "" {
+			redirectOauth2Error(ctx, oauth2Err)
+			ctx.Abort()
+			return
+		}
+
+		ctx.HTML(oauth2Err.Status(), "error", gin.H{
+			"Status":      oauth2Err.Status(),
+			"Code":        oauth2Err.Code,
+			"Description": oauth2Err.Description,
+		})
+		ctx.Abort()
+	}
+}
+
+// oauth2Handler renders errors as JSON for the endpoints called by clients directly (RFC 6749 5.2).
+func oauth2Handler(fn func(ctx *gin.Context) error) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		// responses contain tokens or credentials (RFC 6749 5.1)
+		ctx.Header("Cache-Control", "no-store")
+		ctx.Header("Pragma", "no-cache")
+
+		err := fn(ctx)
+		if err == nil {
+			return
+		}
+
+		oauth2Err := asOauth2Error(ctx, err)
+		if oauth2Err.Code == Oauth2ErrorInvalidClient {
+			ctx.Header("WWW-Authenticate", `Basic realm="simpleident"`)
+		}
+		ctx.AbortWithStatusJSON(oauth2Err.Status(), oauth2Err.response())
+	}
+}
+
+// bearerHandler renders errors of protected resources with the WWW-Authenticate header (RFC 6750 3).
+func bearerHandler(fn func(ctx *gin.Context) error) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		err := fn(ctx)
+		if err == nil {
+			return
+		}
+
+		oauth2Err := asOauth2Error(ctx, err)
+		challenge := `Bearer realm="simpleident"`
+		if oauth2Err.Code == "" {
+			ctx.Header("WWW-Authenticate", challenge)
+			ctx.AbortWithStatus(oauth2Err.Status())
+			return
+		}
+		if oauth2Err.Code != Oauth2ErrorServerError {
+			challenge += fmt.Sprintf(`, error="%s"`, oauth2Err.Code)
+			if oauth2Err.Description != "" {
+				challenge += fmt.Sprintf(`, error_description="%s"`, oauth2Err.Description)
+			}
+		}
+		ctx.Header("WWW-Authenticate", challenge)
+		ctx.AbortWithStatusJSON(oauth2Err.Status(), oauth2Err.response())
+	}
+}
+
+func redirectOauth2Error(ctx *gin.Context, oauth2Err *Oauth2Error) {
+	u, err := url.Parse(oauth2Err.RedirectURI)
+	if err != nil {
+		_ = ctx.Error(err)
+		ctx.HTML(http.StatusBadRequest, "error", gin.H{
+			"Status": http.StatusBadRequest,
+			"Code":   Oauth2ErrorInvalidRequest,
+		})
+		return
+	}
+
+	q := u.Query()
+	q.Set("error", string(oauth2Err.Code))
+	if oauth2Err.Description != "" {
+		q.Set("error_description", oauth2Err.Description)
+	}
+	if oauth2Err.State != "" {
+		q.Set("state", oauth2Err.State)
+	}
+	u.RawQuery = q.Encode()
+
+	ctx.Redirect(http.StatusFound, u.String())
+}
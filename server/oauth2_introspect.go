@@ -0,0 +1,123 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ophum/simpleident/models"
+	"gorm.io/gorm"
+)
+
+type Oauth2IntrospectRequest struct {
+	Token         string              `form:"token"`
+	TokenTypeHint Oauth2TokenTypeHint `form:"token_type_hint"`
+}
+
+func (s *Server) oauth2PostIntrospect(ctx *gin.Context) error {
+	client, err := s.oauth2AuthenticateClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	// public clients cannot be authenticated, so they are never allowed to introspect.
+	if len(client.ClientSecrets) == 0 {
+		return errInvalidClient
+	}
+	if !client.IsResourceServer {
+		return newOauth2Error(Oauth2ErrorUnauthorizedClient, "client is not a resource server")
+	}
+
+	var req Oauth2IntrospectRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		return newOauth2Error(Oauth2ErrorInvalidRequest, err.Error())
+	}
+	if req.Token == "" {
+		return newOauth2Error(Oauth2ErrorInvalidRequest, "token required")
+	}
+
+	introspectors := []func(string) (gin.H, error){
+		s.introspectOauth2AccessToken,
+		s.introspectOauth2RefreshToken,
+	}
+	if req.TokenTypeHint == Oauth2TokenTypeHintRefreshToken {
+		introspectors[0], introspectors[1] = introspectors[1], introspectors[0]
+	}
+	for _, introspect := range introspectors {
+		res, err := introspect(req.Token)
+		if err != nil {
+			return err
+		}
+		if res != nil {
+			ctx.JSON(http.StatusOK, res)
+			return nil
+		}
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"active": false,
+	})
+	return nil
+}
+
+func (s *Server) introspectOauth2AccessToken(token string) (gin.H, error) {
+	var accessToken models.Oauth2Token
+	if err := s.db.Preload("Account").
+		Where("token = ?", token).
+		First(&accessToken).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if accessToken.ExpiresAt.Before(time.Now()) {
+		return gin.H{"active": false}, nil
+	}
+
+	res := gin.H{
+		"active":     true,
+		"token_type": "Bearer",
+		"client_id":  accessToken.Oauth2ClientID,
+		"scope":      accessToken.Scope,
+		"sub":        accessToken.Oauth2ClientID,
+		"exp":        accessToken.ExpiresAt.Unix(),
+		"iat":        accessToken.CreatedAt.Unix(),
+	}
+	if accessToken.Account != nil {
+		res["sub"] = accessToken.Account.ID
+		res["username"] = accessToken.Account.Username
+	}
+	return res, nil
+}
+
+func (s *Server) introspectOauth2RefreshToken(token string) (gin.H, error) {
+	var refreshToken models.Oauth2RefreshToken
+	if err := s.db.Where("token = ?", token).First(&refreshToken).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if refreshToken.UsedAt != nil || refreshToken.ExpiresAt.Before(time.Now()) {
+		return gin.H{"active": false}, nil
+	}
+
+	var account models.Account
+	if err := s.db.Where("id = ?", refreshToken.AccountID).First(&account).Error; err != nil {
+		return nil, err
+	}
+
+	return gin.H{
+		"active":     true,
+		"token_type": "refresh_token",
+		"client_id":  refreshToken.Oauth2ClientID,
+		"scope":      refreshToken.Scope,
+		"sub":        account.ID,
+		"username":   account.Username,
+		"exp":        refreshToken.ExpiresAt.Unix(),
+		"iat":        refreshToken.CreatedAt.Unix(),
+	}, nil
+}
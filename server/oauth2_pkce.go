@@ -0,0 +1,93 @@
+package server
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"regexp"
+
+	"github.com/ophum/simpleident/models"
+)
+
+type Oauth2CodeChallengeMethod string
+
+const (
+	Oauth2CodeChallengeMethodPlain Oauth2CodeChallengeMethod = "plain"
+	Oauth2CodeChallengeMethodS256  Oauth2CodeChallengeMethod = "S256"
+)
+
+// code_verifier and code_challenge share the same syntax (RFC 7636 4.1, 4.2).
+var pkceValueRegexp = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+
+// pkceRequired reports whether the client must use PKCE with S256.
+// Public clients have no secret to authenticate with at the token endpoint,
+// so PKCE is the only protection of their codes against interception (RFC 9700 2.1.1).
+// ClientSecrets must be preloaded.
+func pkceRequired(client *models.Oauth2Client) bool {
+	return client.RequirePKCE || len(client.ClientSecrets) == 0
+}
+
+// validatePKCEChallenge validates the PKCE parameters of an authorization request
+// and returns the effective code_challenge_method.
+func validatePKCEChallenge(challenge string, method Oauth2CodeChallengeMethod, required bool) (Oauth2CodeChallengeMethod, error) {
+	if challenge == "" {
+		if method != "" {
+			return "", errors.New("code_challenge_method without code_challenge")
+		}
+		if required {
+			return "", errors.New("code_challenge required")
+		}
+		return "", nil
+	}
+
+	if !pkceValueRegexp.MatchString(challenge) {
+		return "", errors.New("invalid code_challenge")
+	}
+
+	// defaults to "plain" if not present in the request.
+	if method == "" {
+		method = Oauth2CodeChallengeMethodPlain
+	}
+	switch method {
+	case Oauth2CodeChallengeMethodS256:
+	case Oauth2CodeChallengeMethodPlain:
+		// clients which are required to use PKCE must not downgrade to plain.
+		if required {
+			return "", errors.New("code_challenge_method plain is not allowed")
+		}
+	default:
+		return "", errors.New("unsupported code_challenge_method")
+	}
+	return method, nil
+}
+
+// verifyPKCE verifies code_verifier against the code_challenge bound to an authorization code.
+func verifyPKCE(challenge string, method Oauth2CodeChallengeMethod, verifier string) error {
+	if challenge == "" {
+		// a code_verifier for a code issued without code_challenge indicates a downgrade attempt.
+		if verifier != "" {
+			return errors.New("unexpected code_verifier")
+		}
+		return nil
+	}
+
+	if !pkceValueRegexp.MatchString(verifier) {
+		return errors.New("invalid code_verifier")
+	}
+
+	computed := verifier
+	switch method {
+	case Oauth2CodeChallengeMethodS256:
+		sum := sha256.Sum256([]byte(verifier))
+		computed = base64.RawURLEncoding.EncodeToString(sum[:])
+	case Oauth2CodeChallengeMethodPlain:
+	default:
+		return errors.New("unsupported code_challenge_method")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) != 1 {
+		return errors.New("code_verifier mismatch")
+	}
+	return nil
+}
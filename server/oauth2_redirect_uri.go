@@ -0,0 +1,77 @@
+package server
+
+import (
+	"errors"
+	"net"
+	"net/url"
+
+	"github.com/ophum/simpleident/models"
+)
+
+var errInvalidRedirectURI = errors.New("invalid redirect_uri")
+
+// validateRedirectURI checks a redirect URI before it is registered to a client (RFC 6749 3.1.2).
+func validateRedirectURI(s string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return errInvalidRedirectURI
+	}
+	if !u.IsAbs() || u.Fragment != "" {
+		return errInvalidRedirectURI
+	}
+	return nil
+}
+
+// matchRedirectURI finds the registered redirect URI the requested one refers to.
+// An omitted redirect_uri is only accepted when exactly one is registered (RFC 6749 3.1.2.3).
+func matchRedirectURI(registered []*models.Oauth2ClientRedirectURI, requested string) (string, error) {
+	if requested == "" {
+		if len(registered) != 1 {
+			return "", errInvalidRedirectURI
+		}
+		return registered[0].URI, nil
+	}
+
+	for _, r := range registered {
+		if r.URI == requested || matchLoopbackRedirectURI(r.URI, requested) {
+			return requested, nil
+		}
+	}
+	return "", errInvalidRedirectURI
+}
+
+// matchLoopbackRedirectURI allows native apps to use any port on a loopback IP address (RFC 8252 7.3).
+func matchLoopbackRedirectURI(registered, requested string) bool {
+	r, err := url.Parse(registered)
+	if err != nil || !isLoopbackRedirectURI(r) {
+		return false
+	}
+	u, err := url.Parse(requested)
+	if err != nil || !isLoopbackRedirectURI(u) {
+		return false
+	}
+	if u.Fragment != "" || u.User != nil {
+		return false
+	}
+
+	return r.Hostname() == u.Hostname() &&
+		r.EscapedPath() == u.EscapedPath() &&
+		r.RawQuery == u.RawQuery
+}
+
+func isLoopbackRedirectURI(u *url.URL) bool {
+	if u.Scheme != "http" {
+		return false
+	}
+	ip := net.ParseIP(u.Hostname())
+	return ip != nil && ip.IsLoopback()
+}
+
+// verifyCodeRedirectURI checks the redirect_uri of the token request against the one of the authorization request.
+// It is required if it was included in the authorization request (RFC 6749 4.1.3).
+func verifyCodeRedirectURI(authorized, requested string) error {
+	if authorized != "" && authorized != requested {
+		return errInvalidRedirectURI
+	}
+	return nil
+}
@@ -0,0 +1,255 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/ophum/simpleident/models"
+)
+
+func TestValidateRedirectURI(t *testing.T) {
+	tests := []struct {
+		uri   string
+		valid bool
+	}{
+		{"https://app.example.com/callback", true},
+		{"https://app.example.com/callback?foo=bar", true},
+		{"http://127.0.0.1/callback", true},
+		{"com.example.app:/callback", true},
+		{"not-a-uri", false},
+		{"/callback", false},
+		{"//app.example.com/callback", false},
+		{"https://app.example.com/callback#fragment", false},
+		{"https://app.example.com/%zz", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			err := validateRedirectURI(tt.uri)
+			if tt.valid && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("accepted")
+			}
+		})
+	}
+}
+
+func TestMatchRedirectURI(t *testing.T) {
+	registered := func(uris ...string) []*models.Oauth2ClientRedirectURI {
+		var r []*models.Oauth2ClientRedirectURI
+		for _, uri := range uris {
+			r = append(r, &models.Oauth2ClientRedirectURI{URI: uri})
+		}
+		return r
+	}
+
+	tests := []struct {
+		name       string
+		registered []*models.Oauth2ClientRedirectURI
+		requested  string
+		want       string
+	}{
+		{
+			name:       "exact match",
+			registered: registered("https://app.example.com/callback"),
+			requested:  "https://app.example.com/callback",
+			want:       "https://app.example.com/callback",
+		},
+		{
+			name:       "one of registered",
+			registered: registered("https://app.example.com/callback", "https://app.example.com/other"),
+			requested:  "https://app.example.com/other",
+			want:       "https://app.example.com/other",
+		},
+		{
+			name:       "omitted with one registered",
+			registered: registered("https://app.example.com/callback"),
+			requested:  "",
+			want:       "https://app.example.com/callback",
+		},
+		{
+			name:       "omitted with several registered",
+			registered: registered("https://app.example.com/callback", "https://app.example.com/other"),
+			requested:  "",
+		},
+		{
+			name:       "omitted with none registered",
+			registered: nil,
+			requested:  "",
+		},
+		{
+			name:       "host suffix",
+			registered: registered("https://app.example.com/callback"),
+			requested:  "https://app.example.com.evil.net/callback",
+		},
+		{
+			name:       "prefix",
+			registered: registered("https://app.example.com"),
+			requested:  "https://app.example.com.evil.net",
+		},
+		{
+			name:       "userinfo",
+			registered: registered("https://app.example.com/callback"),
+			requested:  "https://app.example.com@evil.net/callback",
+		},
+		{
+			name:       "subdomain",
+			registered: registered("https://app.example.com/callback"),
+			requested:  "https://evil.app.example.com/callback",
+		},
+		{
+			name:       "path suffix",
+			registered: registered("https://app.example.com/callback"),
+			requested:  "https://app.example.com/callback/evil",
+		},
+		{
+			name:       "path traversal",
+			registered: registered("https://app.example.com/callback"),
+			requested:  "https://app.example.com/callback/../evil",
+		},
+		{
+			name:       "trailing slash",
+			registered: registered("https://app.example.com/callback"),
+			requested:  "https://app.example.com/callback/",
+		},
+		{
+			name:       "added query",
+			registered: registered("https://app.example.com/callback"),
+			requested:  "https://app.example.com/callback?next=https://evil.net",
+		},
+		{
+			name:       "changed query",
+			registered: registered("https://app.example.com/callback?tenant=a"),
+			requested:  "https://app.example.com/callback?tenant=b",
+		},
+		{
+			name:       "same query",
+			registered: registered("https://app.example.com/callback?tenant=a"),
+			requested:  "https://app.example.com/callback?tenant=a",
+			want:       "https://app.example.com/callback?tenant=a",
+		},
+		{
+			name:       "fragment",
+			registered: registered("https://app.example.com/callback"),
+			requested:  "https://app.example.com/callback#evil",
+		},
+		{
+			name:       "scheme",
+			registered: registered("https://app.example.com/callback"),
+			requested:  "http://app.example.com/callback",
+		},
+		{
+			name:       "port on non-loopback host",
+			registered: registered("https://app.example.com/callback"),
+			requested:  "https://app.example.com:8443/callback",
+		},
+		{
+			name:       "changed port on non-loopback host",
+			registered: registered("http://app.example.com:8080/callback"),
+			requested:  "http://app.example.com:8081/callback",
+		},
+		{
+			name:       "port on localhost name",
+			registered: registered("http://localhost/callback"),
+			requested:  "http://localhost:51004/callback",
+		},
+		{
+			name:       "loopback any port",
+			registered: registered("http://127.0.0.1/callback"),
+			requested:  "http://127.0.0.1:51004/callback",
+			want:       "http://127.0.0.1:51004/callback",
+		},
+		{
+			name:       "loopback changed port",
+			registered: registered("http://127.0.0.1:8080/callback"),
+			requested:  "http://127.0.0.1:51004/callback",
+			want:       "http://127.0.0.1:51004/callback",
+		},
+		{
+			name:       "ipv6 loopback any port",
+			registered: registered("http://[::1]/callback"),
+			requested:  "http://[::1]:51004/callback",
+			want:       "http://[::1]:51004/callback",
+		},
+		{
+			name:       "loopback other address",
+			registered: registered("http://127.0.0.1/callback"),
+			requested:  "http://[::1]:51004/callback",
+		},
+		{
+			name:       "loopback path",
+			registered: registered("http://127.0.0.1/callback"),
+			requested:  "http://127.0.0.1:51004/evil",
+		},
+		{
+			name:       "loopback query",
+			registered: registered("http://127.0.0.1/callback"),
+			requested:  "http://127.0.0.1:51004/callback?evil=1",
+		},
+		{
+			name:       "loopback fragment",
+			registered: registered("http://127.0.0.1/callback"),
+			requested:  "http://127.0.0.1:51004/callback#evil",
+		},
+		{
+			name:       "loopback userinfo",
+			registered: registered("http://127.0.0.1/callback"),
+			requested:  "http://evil@127.0.0.1:51004/callback",
+		},
+		{
+			name:       "loopback https",
+			registered: registered("http://127.0.0.1/callback"),
+			requested:  "https://127.0.0.1:51004/callback",
+		},
+		{
+			name:       "loopback requested for non-loopback registration",
+			registered: registered("https://app.example.com/callback"),
+			requested:  "http://127.0.0.1:51004/callback",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := matchRedirectURI(tt.registered, tt.requested)
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("accepted as %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyCodeRedirectURI(t *testing.T) {
+	tests := []struct {
+		name       string
+		authorized string
+		requested  string
+		valid      bool
+	}{
+		{"same", "https://app.example.com/callback", "https://app.example.com/callback", true},
+		{"omitted in both", "", "", true},
+		{"omitted in the authorization request", "", "https://app.example.com/callback", true},
+		{"omitted in the token request", "https://app.example.com/callback", "", false},
+		{"different", "https://app.example.com/callback", "https://app.example.com/other", false},
+		{"host suffix", "https://app.example.com/callback", "https://app.example.com.evil.net/callback", false},
+		{"loopback port", "http://127.0.0.1:51004/callback", "http://127.0.0.1:51005/callback", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyCodeRedirectURI(tt.authorized, tt.requested)
+			if tt.valid && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("accepted")
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ophum/simpleident/models"
+	"gorm.io/gorm"
+)
+
+func (s *Server) oauth2TokenRefreshToken(ctx *gin.Context, client *models.Oauth2Client, req *Oauth2TokenRequest) error {
+	if req.RefreshToken == "" {
+		return newOauth2Error(Oauth2ErrorInvalidRequest, "refresh_token required")
+	}
+
+	var refreshToken models.Oauth2RefreshToken
+	if err := s.db.Where("token = ?", req.RefreshToken).
+		First(&refreshToken).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return newOauth2Error(Oauth2ErrorInvalidGrant, "invalid refresh token")
+		}
+		return err
+	}
+
+	if refreshToken.Oauth2ClientID != client.ID {
+		return newOauth2Error(Oauth2ErrorInvalidGrant, "refresh token was issued to another client")
+	}
+
+	now := time.Now()
+	if refreshToken.ExpiresAt.Before(now) {
+		return newOauth2Error(Oauth2ErrorInvalidGrant, "refresh token expired")
+	}
+
+	// the requested scope must not include any scope not originally granted (RFC 6749 6).
+	scope, err := resolveScope(req.Scope, refreshToken.Scope)
+	if err != nil {
+		return newOauth2Error(Oauth2ErrorInvalidScope, "")
+	}
+
+	var res *Oauth2TokenResponse
+	reused := false
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		// marking the refresh token as used only succeeds once, even for concurrent requests.
+		result := tx.Model(&models.Oauth2RefreshToken{}).
+			Where("id = ? AND used_at IS NULL", refreshToken.ID).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		// a rotated refresh token is presented again: either the legitimate client or an attacker holds a stolen copy,
+		// so the whole family is revoked (RFC 9700 4.14.2).
+		if result.RowsAffected == 0 {
+			reused = true
+			return revokeOauth2TokenFamily(tx, refreshToken.FamilyID)
+		}
+
+		var err error
+		res, err = s.issueOauth2Tokens(ctx, tx, &oauth2TokenGrant{
+			Client:            client,
+			AccountID:         &refreshToken.AccountID,
+			FamilyID:          refreshToken.FamilyID,
+			Scope:             scope,
+			RefreshTokenScope: refreshToken.Scope,
+			Resource:          req.Resource,
+			WithRefreshToken:  true,
+		})
+		return err
+	}); err != nil {
+		return err
+	}
+	if reused {
+		return newOauth2Error(Oauth2ErrorInvalidGrant, "refresh token reused")
+	}
+
+	ctx.JSON(http.StatusOK, res)
+	return nil
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"errors"
+	"net/url"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ophum/simpleident/models"
+)
+
+// validateResourceIndicator checks a resource indicator is an absolute URI without a fragment (RFC 8707 2).
+func validateResourceIndicator(resource string) error {
+	u, err := url.Parse(resource)
+	if err != nil {
+		return err
+	}
+	if !u.IsAbs() {
+		return errors.New("resource must be an absolute URI")
+	}
+	if u.Fragment != "" {
+		return errors.New("resource must not include a fragment")
+	}
+	return nil
+}
+
+// oauth2RequestedResource returns the resource parameter of a token request,
+// which must be one of the audiences registered to the client.
+func oauth2RequestedResource(ctx *gin.Context, client *models.Oauth2Client) (string, error) {
+	resources := ctx.PostFormArray("resource")
+	switch len(resources) {
+	case 0:
+		return "", nil
+	case 1:
+	default:
+		return "", newOauth2Error(Oauth2ErrorInvalidTarget, "only one resource is supported")
+	}
+
+	if err := validateResourceIndicator(resources[0]); err != nil {
+		return "", newOauth2Error(Oauth2ErrorInvalidTarget, err.Error())
+	}
+	if !slices.Contains(parseScope(client.Audiences), resources[0]) {
+		return "", newOauth2Error(Oauth2ErrorInvalidTarget, "resource is not registered to the client")
+	}
+	return resources[0], nil
+}
+
+// accessTokenAudience returns the aud claim of a JWT access token: the requested resource,
+// the first audience registered to the client, or the issuer itself, whose only resource is /api/userinfo.
+func accessTokenAudience(grant *oauth2TokenGrant, issuer string) string {
+	if grant.Resource != "" {
+		return grant.Resource
+	}
+	if audiences := parseScope(grant.Client.Audiences); len(audiences) > 0 {
+		return audiences[0]
+	}
+	return issuer
+}
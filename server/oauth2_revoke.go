@@ -0,0 +1,95 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ophum/simpleident/models"
+	"gorm.io/gorm"
+)
+
+type Oauth2TokenTypeHint string
+
+const (
+	Oauth2TokenTypeHintAccessToken  Oauth2TokenTypeHint = "access_token"
+	Oauth2TokenTypeHintRefreshToken Oauth2TokenTypeHint = "refresh_token"
+)
+
+type Oauth2RevokeRequest struct {
+	Token         string              `form:"token"`
+	TokenTypeHint Oauth2TokenTypeHint `form:"token_type_hint"`
+}
+
+func (s *Server) oauth2PostRevoke(ctx *gin.Context) error {
+	client, err := s.oauth2AuthenticateClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	var req Oauth2RevokeRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		return newOauth2Error(Oauth2ErrorInvalidRequest, err.Error())
+	}
+	if req.Token == "" {
+		return newOauth2Error(Oauth2ErrorInvalidRequest, "token required")
+	}
+
+	// the hint only decides the lookup order, other token types are searched as well (RFC 7009 2.1).
+	revokers := []func(*models.Oauth2Client, string) (bool, error){
+		s.revokeOauth2AccessToken,
+		s.revokeOauth2RefreshToken,
+	}
+	if req.TokenTypeHint == Oauth2TokenTypeHintRefreshToken {
+		revokers[0], revokers[1] = revokers[1], revokers[0]
+	}
+	for _, revoke := range revokers {
+		found, err := revoke(client, req.Token)
+		if err != nil {
+			return err
+		}
+		if found {
+			break
+		}
+	}
+
+	// invalid tokens and tokens of other clients do not cause an error response,
+	// so the client cannot learn anything about them.
+	ctx.Status(http.StatusOK)
+	return nil
+}
+
+func (s *Server) revokeOauth2AccessToken(client *models.Oauth2Client, token string) (bool, error) {
+	var accessToken models.Oauth2Token
+	if err := s.db.Where("token = ?", token).First(&accessToken).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if accessToken.Oauth2ClientID != client.ID {
+		return true, nil
+	}
+
+	return true, s.db.Delete(&accessToken).Error
+}
+
+func (s *Server) revokeOauth2RefreshToken(client *models.Oauth2Client, token string) (bool, error) {
+	var refreshToken models.Oauth2RefreshToken
+	if err := s.db.Where("token = ?", token).First(&refreshToken).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if refreshToken.Oauth2ClientID != client.ID {
+		return true, nil
+	}
+
+	// access tokens issued with the refresh token are invalidated as well.
+	return true, s.db.Transaction(func(tx *gorm.DB) error {
+		return revokeOauth2TokenFamily(tx, refreshToken.FamilyID)
+	})
+}
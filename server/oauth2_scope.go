@@ -0,0 +1,83 @@
+package server
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+const (
+	Oauth2ScopeOpenID  = "openid"
+	Oauth2ScopeProfile = "profile"
+)
+
+var errInvalidScope = errors.New("invalid scope")
+
+// descriptions shown on the consent page, scopes without a description are shown as is.
+var oauth2ScopeDescriptions = map[string]string{
+	Oauth2ScopeOpenID:  "あなたを識別するID",
+	Oauth2ScopeProfile: "ユーザー名",
+}
+
+type Oauth2ScopeItem struct {
+	Name        string
+	Description string
+}
+
+// parseScope parses a space-delimited scope string (RFC 6749 3.3).
+func parseScope(scope string) []string {
+	scopes := []string{}
+	for _, s := range strings.Fields(scope) {
+		if !slices.Contains(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
+
+func formatScope(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+// resolveScope validates the requested scope against the allowed scope.
+// The allowed scope is granted if no scope is requested.
+func resolveScope(requested, allowed string) (string, error) {
+	allowedScopes := parseScope(allowed)
+	requestedScopes := parseScope(requested)
+	if len(requestedScopes) == 0 {
+		return formatScope(allowedScopes), nil
+	}
+
+	for _, s := range requestedScopes {
+		if !slices.Contains(allowedScopes, s) {
+			return "", errInvalidScope
+		}
+	}
+	return formatScope(requestedScopes), nil
+}
+
+// containsScope reports whether every scope in scope is also in granted.
+func containsScope(granted, scope string) bool {
+	grantedScopes := parseScope(granted)
+	for _, s := range parseScope(scope) {
+		if !slices.Contains(grantedScopes, s) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasScope(scope, s string) bool {
+	return slices.Contains(parseScope(scope), s)
+}
+
+func oauth2ScopeItems(scope string) []Oauth2ScopeItem {
+	items := []Oauth2ScopeItem{}
+	for _, s := range parseScope(scope) {
+		items = append(items, Oauth2ScopeItem{
+			Name:        s,
+			Description: oauth2ScopeDescriptions[s],
+		})
+	}
+	return items
+}
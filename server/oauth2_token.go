@@ -0,0 +1,123 @@
+package server
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ophum/simpleident/models"
+	"gorm.io/gorm"
+)
+
+type Oauth2TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+}
+
+type oauth2TokenGrant struct {
+	Client *models.Oauth2Client
+	// nil for tokens issued to the client itself
+	AccountID *uuid.UUID
+	FamilyID  uuid.UUID
+	Scope     string
+	// scope of the refresh token, which keeps the scope originally granted even if the access token is narrowed (RFC 6749 6).
+	// Scope is used if empty.
+	RefreshTokenScope string
+	// resource requested in the token request, see accessTokenAudience
+	Resource string
+
+	WithRefreshToken bool
+}
+
+// issueOauth2Tokens issues an access token, and a refresh token if requested, belonging to the token family of grant.
+// The access token is opaque or a JWT according to the access token format of the client.
+func (s *Server) issueOauth2Tokens(ctx *gin.Context, tx *gorm.DB, grant *oauth2TokenGrant) (*Oauth2TokenResponse, error) {
+	now := time.Now()
+	expiresAt := now.Add(s.config.AccessTokenLifetime)
+
+	tokenID, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+
+	var token string
+	if grant.Client.AccessTokenFormat == models.Oauth2AccessTokenFormatJWT {
+		token, err = s.issueJWTAccessToken(ctx, grant, tokenID, now, expiresAt)
+	} else {
+		token, err = generateSecret(20)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// JWT access tokens are stored as well, so they can be revoked and introspected.
+	if err := tx.Create(&models.Oauth2Token{
+		Model: models.Model{
+			ID: tokenID,
+		},
+		Oauth2ClientID: grant.Client.ID,
+		Token:          token,
+		AccountID:      grant.AccountID,
+		FamilyID:       grant.FamilyID,
+		Scope:          grant.Scope,
+		ExpiresAt:      expiresAt,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	res := &Oauth2TokenResponse{
+		AccessToken: token,
+		TokenType:   "bearer",
+		ExpiresIn:   int(s.config.AccessTokenLifetime.Seconds()),
+		Scope:       grant.Scope,
+	}
+	if !grant.WithRefreshToken || grant.AccountID == nil {
+		return res, nil
+	}
+
+	refreshTokenScope := grant.RefreshTokenScope
+	if refreshTokenScope == "" {
+		refreshTokenScope = grant.Scope
+	}
+
+	refreshToken, err := generateSecret(40)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshTokenID, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Create(&models.Oauth2RefreshToken{
+		Model: models.Model{
+			ID: refreshTokenID,
+		},
+		Oauth2ClientID: grant.Client.ID,
+		Token:          refreshToken,
+		AccountID:      *grant.AccountID,
+		FamilyID:       grant.FamilyID,
+		Scope:          refreshTokenScope,
+		ExpiresAt:      now.Add(s.config.RefreshTokenLifetime),
+	}).Error; err != nil {
+		return nil, err
+	}
+	res.RefreshToken = refreshToken
+
+	return res, nil
+}
+
+// revokeOauth2TokenFamily revokes every access token and refresh token in the token family familyID.
+func revokeOauth2TokenFamily(tx *gorm.DB, familyID uuid.UUID) error {
+	if err := tx.Where("family_id = ?", familyID).
+		Delete(&models.Oauth2Token{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("family_id = ?", familyID).
+		Delete(&models.Oauth2RefreshToken{}).Error
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/google/uuid"
+	"github.com/ophum/simpleident/keys"
+	"github.com/ophum/simpleident/models"
+)
+
+type IDTokenClaims struct {
+	Issuer    string `json:"iss"`
+	Subject   string `json:"sub"`
+	Audience  string `json:"aud"`
+	ExpiresAt int64  `json:"exp"`
+	IssuedAt  int64  `json:"iat"`
+	AuthTime  int64  `json:"auth_time,omitempty"`
+	Nonce     string `json:"nonce,omitempty"`
+}
+
+// issueIDToken issues an OpenID Connect ID token (OpenID Connect Core 1.0 2).
+func (s *Server) issueIDToken(ctx *gin.Context, client *models.Oauth2Client, accountID uuid.UUID, authTime time.Time, nonce string) (string, error) {
+	now := time.Now()
+	claims := &IDTokenClaims{
+		Issuer:    s.issuer(ctx),
+		Subject:   accountID.String(),
+		Audience:  client.ID.String(),
+		ExpiresAt: now.Add(s.config.IDTokenLifetime).Unix(),
+		IssuedAt:  now.Unix(),
+		Nonce:     nonce,
+	}
+	if !authTime.IsZero() {
+		claims.AuthTime = authTime.Unix()
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	return s.keys.Sign(idTokenSigningAlg(client), "JWT", payload)
+}
+
+func idTokenSigningAlg(client *models.Oauth2Client) jose.SignatureAlgorithm {
+	if client.IDTokenSigningAlg == "" {
+		return keys.RS256
+	}
+	return jose.SignatureAlgorithm(client.IDTokenSigningAlg)
+}
+
+func (s *Server) issuer(ctx *gin.Context) string {
+	if s.config.Issuer != "" {
+		return s.config.Issuer
+	}
+	return requestBaseURL(ctx)
+}
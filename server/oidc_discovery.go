@@ -0,0 +1,84 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ophum/simpleident/keys"
+)
+
+// registerDiscoveryRoutes registers the discovery endpoints.
+// It has to be called after every other route is registered, since only registered endpoints are advertised.
+func (s *Server) registerDiscoveryRoutes(r *gin.Engine) {
+	routes := r.Routes()
+	r.GET("/.well-known/openid-configuration", handler(func(ctx *gin.Context) error {
+		return s.oidcDiscovery(ctx, routes)
+	}))
+	r.GET("/.well-known/jwks.json", handler(s.oidcJWKS))
+}
+
+// oidcDiscovery serves the OpenID Provider Metadata (OpenID Connect Discovery 1.0 3).
+func (s *Server) oidcDiscovery(ctx *gin.Context, routes gin.RoutesInfo) error {
+	issuer := s.issuer(ctx)
+	res := gin.H{
+		"issuer":   issuer,
+		"jwks_uri": issuer + "/.well-known/jwks.json",
+	}
+
+	endpoints := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"authorization_endpoint", http.MethodGet, "/oauth2/authorize"},
+		{"token_endpoint", http.MethodPost, "/oauth2/token"},
+		{"userinfo_endpoint", http.MethodGet, "/api/userinfo"},
+		{"revocation_endpoint", http.MethodPost, "/oauth2/revoke"},
+		{"introspection_endpoint", http.MethodPost, "/oauth2/introspect"},
+		{"device_authorization_endpoint", http.MethodPost, "/oauth2/device_authorization"},
+	}
+	for _, e := range endpoints {
+		if slices.ContainsFunc(routes, func(route gin.RouteInfo) bool {
+			return route.Method == e.method && route.Path == e.path
+		}) {
+			res[e.name] = issuer + e.path
+		}
+	}
+
+	grantTypes := []string{}
+	for grantType := range s.oauth2TokenGrants() {
+		grantTypes = append(grantTypes, string(grantType))
+	}
+	slices.Sort(grantTypes)
+
+	algs := []string{}
+	for _, alg := range keys.Algorithms {
+		algs = append(algs, string(alg))
+	}
+
+	authMethods := []string{"client_secret_basic", "client_secret_post", "none"}
+
+	res["response_types_supported"] = []Oauth2ResponseType{Oauth2ResponseTypeCode}
+	res["response_modes_supported"] = []string{"query"}
+	res["grant_types_supported"] = grantTypes
+	res["subject_types_supported"] = []string{"public"}
+	res["id_token_signing_alg_values_supported"] = algs
+	res["scopes_supported"] = []string{Oauth2ScopeOpenID, Oauth2ScopeProfile}
+	res["claims_supported"] = []string{"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce", "preferred_username"}
+	res["token_endpoint_auth_methods_supported"] = authMethods
+	res["revocation_endpoint_auth_methods_supported"] = authMethods
+	res["introspection_endpoint_auth_methods_supported"] = []string{"client_secret_basic", "client_secret_post"}
+	res["code_challenge_methods_supported"] = []Oauth2CodeChallengeMethod{
+		Oauth2CodeChallengeMethodS256,
+		Oauth2CodeChallengeMethodPlain,
+	}
+
+	ctx.JSON(http.StatusOK, res)
+	return nil
+}
+
+func (s *Server) oidcJWKS(ctx *gin.Context) error {
+	ctx.JSON(http.StatusOK, s.keys.PublicKeys())
+	return nil
+}
@@ -3,10 +3,13 @@ package server
 import (
 	"errors"
 	"net/http"
+	"sync/atomic"
+	"time"
 
 	"github.com/gin-contrib/sessions"
 
 	"github.com/gin-gonic/gin"
+	"github.com/ophum/simpleident/keys"
 	"github.com/ophum/simpleident/models"
 	"golang.org/x/crypto/bcrypt"
 
@@ -15,29 +18,30 @@ import (
 )
 
 type Server struct {
-	db                *gorm.DB
-	enableAdminServer bool
+	db     *gorm.DB
+	keys   *keys.Manager
+	config *Config
+
+	draining atomic.Bool
 }
 
-func NewServer(db *gorm.DB, enableAdminServer bool) *Server {
+func NewServer(db *gorm.DB, keys *keys.Manager, config *Config) *Server {
+	if config == nil {
+		config = DefaultConfig()
+	}
 	return &Server{
-		db:                db,
-		enableAdminServer: enableAdminServer,
+		db:     db,
+		keys:   keys,
+		config: config,
 	}
 }
 
 func (s *Server) RegisterRoutes(r *gin.Engine) {
 	{
 		r := r.Group("")
-		r.Use(csrf.Middleware(csrf.Options{
-			Secret: "secret",
-			ErrorFunc: func(ctx *gin.Context) {
-				ctx.String(http.StatusBadRequest, "CSRF token mismatch")
-				ctx.Abort()
-			},
-		}))
-
-		if s.enableAdminServer {
+		r.Use(s.csrfMiddleware())
+
+		if s.config.EnableAdmin {
 			s.registerAdminRoutes(r)
 		}
 
@@ -45,28 +49,68 @@ func (s *Server) RegisterRoutes(r *gin.Engine) {
 		r.GET("/sign-in", handler(s.signIn))
 		r.POST("/sign-in", handler(s.signInProcess))
 		r.GET("/userinfo", handler(s.userinfo))
+		r.GET("/connected-apps", handler(s.connectedApps))
+		r.POST("/connected-apps/:id/revoke", handler(s.connectedAppsRevoke))
 		r.POST("/sign-out", handler(s.signOut))
 		r.GET("/oauth2/authorize", handler(s.oauth2Authorize))
 		r.POST("/oauth2/authorize", handler(s.oauth2PostAuthorize))
+		r.GET("/device", handler(s.device))
+		r.POST("/device", handler(s.devicePost))
 	}
 
-	r.POST("/oauth2/token", handler(s.oauth2PostToken))
-	r.GET("/api/userinfo", handler(s.apiGetUserinfo))
+	r.POST("/oauth2/token", oauth2Handler(s.oauth2PostToken))
+	r.POST("/oauth2/device_authorization", oauth2Handler(s.oauth2PostDeviceAuthorization))
+	r.POST("/oauth2/revoke", oauth2Handler(s.oauth2PostRevoke))
+	r.POST("/oauth2/introspect", oauth2Handler(s.oauth2PostIntrospect))
+	r.GET("/api/userinfo", bearerHandler(s.apiGetUserinfo))
+	s.registerHealthRoutes(r)
+
+	s.registerDiscoveryRoutes(r)
+}
+
+// RegisterAdminRoutes registers the admin UI to an engine of its own,
+// so that it can be served on a listener which is not exposed publicly.
+func (s *Server) RegisterAdminRoutes(engine *gin.Engine) {
+	r := engine.Group("")
+	r.Use(func(ctx *gin.Context) {
+		ctx.Set(homePathKey, "/")
+	})
+	r.Use(s.csrfMiddleware())
+
+	s.registerAdminRoutes(r)
 
+	r.GET("/", handler(s.adminIndex))
+	r.GET("/sign-in", handler(s.signIn))
+	r.POST("/sign-in", handler(s.signInProcess))
+	r.POST("/sign-out", handler(s.signOut))
+
+	s.registerHealthRoutes(engine)
 }
 
-func handler(fn func(ctx *gin.Context) error) gin.HandlerFunc {
-	return func(ctx *gin.Context) {
-		if err := fn(ctx); err != nil {
-			_ = ctx.Error(err)
+func (s *Server) csrfMiddleware() gin.HandlerFunc {
+	return csrf.Middleware(csrf.Options{
+		Secret: s.config.CSRFSecret,
+		ErrorFunc: func(ctx *gin.Context) {
+			ctx.String(http.StatusBadRequest, "CSRF token mismatch")
 			ctx.Abort()
-			return
-		}
+		},
+	})
+}
+
+const homePathKey = "home_path"
+
+// homePath is where the account is redirected after signing in without a return URL.
+func homePath(ctx *gin.Context) string {
+	if p := ctx.GetString(homePathKey); p != "" {
+		return p
 	}
+	return "/userinfo"
 }
 
 func (s *Server) index(ctx *gin.Context) error {
-	ctx.HTML(http.StatusOK, "index", gin.H{})
+	ctx.HTML(http.StatusOK, "index", gin.H{
+		"EnableAdmin": s.config.EnableAdmin,
+	})
 	return nil
 }
 
@@ -74,7 +118,7 @@ func (s *Server) signIn(ctx *gin.Context) error {
 	session := sessions.Default(ctx)
 
 	if _, ok := session.Get("account_id").(string); ok {
-		ctx.Redirect(http.StatusFound, "/userinfo")
+		ctx.Redirect(http.StatusFound, homePath(ctx))
 		return nil
 	}
 
@@ -96,11 +140,15 @@ type SignInRequest struct {
 func (s *Server) signInProcess(ctx *gin.Context) error {
 	var req SignInRequest
 	if err := ctx.ShouldBind(&req); err != nil {
-		return err
+		return newValidationError(err.Error())
 	}
 
 	var account models.Account
 	if err := s.db.Where("username = ?", req.Username).First(&account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.Redirect(http.StatusFound, "/sign-in")
+			return nil
+		}
 		return err
 	}
 
@@ -115,10 +163,11 @@ func (s *Server) signInProcess(ctx *gin.Context) error {
 	session := sessions.Default(ctx)
 
 	session.Set("account_id", account.ID.String())
+	session.Set("auth_time", time.Now().Unix())
 	session.Save()
 
-	returnURL := "/userinfo"
-	if r, ok := session.Get("return_url").(string); ok {
+	returnURL := homePath(ctx)
+	if r, ok := session.Get("return_url").(string); ok && r != "" {
 		returnURL = r
 	}
 
@@ -0,0 +1,32 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ValidationError is an error of the input submitted to the pages and the admin console, shown as a 400 page.
+// The OAuth 2.0 endpoints report invalid requests with Oauth2Error instead.
+type ValidationError struct {
+	Description string
+}
+
+func newValidationError(description string) *ValidationError {
+	return &ValidationError{
+		Description: description,
+	}
+}
+
+func (e *ValidationError) Error() string {
+	return e.Description
+}
+
+func renderValidationError(ctx *gin.Context, err *ValidationError) {
+	ctx.HTML(http.StatusBadRequest, "error", gin.H{
+		"Status":      http.StatusBadRequest,
+		"Code":        "bad_request",
+		"Description": err.Description,
+	})
+	ctx.Abort()
+}